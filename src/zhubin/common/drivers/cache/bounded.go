@@ -0,0 +1,611 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// How the entries are picked for eviction when a BoundedL1Cache is full.
+type EvictionPolicy int
+
+const (
+	// Least recently used.
+	LRU EvictionPolicy = iota
+	// Least frequently used, the least recently used first among equals.
+	LFU
+	// LRU with an admission filter: a new key only gets in if it was requested more often,
+	// as estimated by a count-min sketch, than the key it would evict.
+	// It keeps the popular keys when the cache is flooded with keys requested once.
+	// NOTE a Set of a new key may therefore not be stored, then the next Get is a miss.
+	TinyLFU
+)
+
+// Minimal in-process cache, implemented by L1Cache, InstrumentedL1Cache and BoundedL1Cache.
+type LocalCache interface {
+	Get(k string) (interface{}, bool)
+	Set(k string, x interface{}, d time.Duration)
+	Delete(k string)
+}
+
+type BoundedOptions struct {
+	// Same as the arguments of NewCache.
+	DefaultExpiration time.Duration
+	CleanupInterval   time.Duration
+
+	// Limits of the cache, 0 means no limit.
+	MaxEntries int
+	MaxBytes   int64
+	// Size of an entry counted against MaxBytes, defaults to the length of the key plus
+	// the length of strings and byte slices, or 64 bytes for other values.
+	Sizer func(k string, x interface{}) int64
+
+	Policy EvictionPolicy
+
+	// Number of shards, each with a lock and an equal share of the limits, rounded up to a power of 2.
+	// Defaults to 16, fewer if MaxEntries is too small for each shard to hold 64 entries.
+	Shards int
+}
+
+// Size-bounded and sharded version of L1Cache, with the most common methods of it.
+// The OnEvicted callback is also called for the entries evicted to make room.
+type BoundedL1Cache struct {
+	*boundedCache
+	// see L1Cache for the finalizer trick
+}
+
+type boundedCache struct {
+	defaultExpiration time.Duration
+	shards            []*boundedShard
+	mask              uint64
+	sizer             func(string, interface{}) int64
+
+	mu        sync.RWMutex
+	onEvicted func(string, interface{})
+	stop      chan bool
+}
+
+func NewBoundedCache(opts BoundedOptions) *BoundedL1Cache {
+	n := opts.Shards
+	if n <= 0 {
+		n = 16
+		for n > 1 && opts.MaxEntries > 0 && opts.MaxEntries/n < 64 {
+			n /= 2
+		}
+	}
+	shards := 1
+	for shards < n {
+		shards <<= 1
+	}
+	de := opts.DefaultExpiration
+	if de == 0 {
+		de = -1
+	}
+	c := &boundedCache{
+		defaultExpiration: de,
+		shards:            make([]*boundedShard, shards),
+		mask:              uint64(shards - 1),
+		sizer:             opts.Sizer,
+	}
+	if c.sizer == nil {
+		c.sizer = defaultSizer
+	}
+	for i := range c.shards {
+		s := &boundedShard{
+			items:      make(map[string]*boundedEntry),
+			maxEntries: ceilDiv(int64(opts.MaxEntries), int64(shards)),
+			maxBytes:   ceilDiv(opts.MaxBytes, int64(shards)),
+		}
+		switch opts.Policy {
+		case LRU:
+			s.policy = newLRUPolicy()
+		case LFU:
+			s.policy = &lfuPolicy{}
+		case TinyLFU:
+			s.policy = newTinyLFUPolicy(int(s.maxEntries))
+		default:
+			panic(fmt.Sprintf("unknown eviction policy: %d", opts.Policy))
+		}
+		c.shards[i] = s
+	}
+	C := &BoundedL1Cache{c}
+	if opts.CleanupInterval > 0 {
+		c.stop = make(chan bool)
+		go c.runJanitor(opts.CleanupInterval, c.stop)
+		runtime.SetFinalizer(C, func(C *BoundedL1Cache) { C.stop <- true })
+	}
+	return C
+}
+
+func ceilDiv(a int64, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+func defaultSizer(k string, x interface{}) int64 {
+	switch v := x.(type) {
+	case string:
+		return int64(len(k) + len(v))
+	case []byte:
+		return int64(len(k) + len(v))
+	}
+	return int64(len(k) + 64)
+}
+
+func hashKey(k string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(k))
+	return h.Sum64()
+}
+
+func (c *boundedCache) runJanitor(interval time.Duration, stop chan bool) {
+	ticker := time.NewTicker(interval)
+	for {
+		select {
+		case <-ticker.C:
+			c.DeleteExpired()
+		case <-stop:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func (c *boundedCache) shard(h uint64) *boundedShard {
+	return c.shards[h&c.mask]
+}
+
+func (c *boundedCache) expiration(d time.Duration) int64 {
+	if d == DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	if d > 0 {
+		return time.Now().Add(d).UnixNano()
+	}
+	return 0
+}
+
+func (c *boundedCache) evicted(items []keyAndValue) {
+	if len(items) == 0 {
+		return
+	}
+	c.mu.RLock()
+	f := c.onEvicted
+	c.mu.RUnlock()
+	if f == nil {
+		return
+	}
+	for _, kv := range items {
+		f(kv.key, kv.value)
+	}
+}
+
+// Add an item to the cache, replacing any existing item, see L1Cache.Set.
+// The least valuable items are evicted if the cache is full, with TinyLFU the item may not be stored instead.
+func (c *boundedCache) Set(k string, x interface{}, d time.Duration) {
+	h := hashKey(k)
+	_, evicted := c.shard(h).set(k, h, x, c.sizer(k, x), c.expiration(d), setAlways)
+	c.evicted(evicted)
+}
+
+func (c *boundedCache) SetDefault(k string, x interface{}) {
+	c.Set(k, x, DefaultExpiration)
+}
+
+// Add an item to the cache only if an item doesn't already exist for the given
+// key, or if the existing item has expired. Returns an error otherwise.
+func (c *boundedCache) Add(k string, x interface{}, d time.Duration) error {
+	h := hashKey(k)
+	ok, evicted := c.shard(h).set(k, h, x, c.sizer(k, x), c.expiration(d), setIfAbsent)
+	c.evicted(evicted)
+	if !ok {
+		return fmt.Errorf("Item %s already exists", k)
+	}
+	return nil
+}
+
+// Set a new value for the cache key only if it already exists, and the existing
+// item hasn't expired. Returns an error otherwise.
+func (c *boundedCache) Replace(k string, x interface{}, d time.Duration) error {
+	h := hashKey(k)
+	ok, evicted := c.shard(h).set(k, h, x, c.sizer(k, x), c.expiration(d), setIfPresent)
+	c.evicted(evicted)
+	if !ok {
+		return fmt.Errorf("Item %s doesn't exist", k)
+	}
+	return nil
+}
+
+// Get an item from the cache. Returns the item or nil, and a bool indicating
+// whether the key was found.
+func (c *boundedCache) Get(k string) (interface{}, bool) {
+	x, _, found := c.GetWithExpiration(k)
+	return x, found
+}
+
+// see L1Cache.GetWithExpiration
+func (c *boundedCache) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+	h := hashKey(k)
+	s := c.shard(h)
+	s.mu.Lock()
+	s.policy.record(h)
+	e, found := s.items[k]
+	if !found || e.expired(time.Now().UnixNano()) {
+		s.mu.Unlock()
+		return nil, time.Time{}, false
+	}
+	s.policy.access(e)
+	x, expiration := e.value, e.expiration
+	s.mu.Unlock()
+	if expiration > 0 {
+		return x, time.Unix(0, expiration), true
+	}
+	return x, time.Time{}, true
+}
+
+// Delete an item from the cache. Does nothing if the key is not in the cache.
+func (c *boundedCache) Delete(k string) {
+	s := c.shard(hashKey(k))
+	s.mu.Lock()
+	e, found := s.items[k]
+	if found {
+		s.remove(e)
+	}
+	s.mu.Unlock()
+	if found {
+		c.evicted([]keyAndValue{{k, e.value}})
+	}
+}
+
+// Delete all expired items from the cache.
+func (c *boundedCache) DeleteExpired() {
+	now := time.Now().UnixNano()
+	for _, s := range c.shards {
+		var evicted []keyAndValue
+		s.mu.Lock()
+		for k, e := range s.items {
+			if e.expired(now) {
+				s.remove(e)
+				evicted = append(evicted, keyAndValue{k, e.value})
+			}
+		}
+		s.mu.Unlock()
+		c.evicted(evicted)
+	}
+}
+
+// Sets an (optional) function that is called with the key and value when an
+// item is evicted from the cache, to make room or when it is deleted, but not when it is overwritten.
+func (c *boundedCache) OnEvicted(f func(string, interface{})) {
+	c.mu.Lock()
+	c.onEvicted = f
+	c.mu.Unlock()
+}
+
+// Copies all unexpired items in the cache into a new map and returns it.
+func (c *boundedCache) Items() map[string]Item {
+	m := make(map[string]Item)
+	now := time.Now().UnixNano()
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for k, e := range s.items {
+			if !e.expired(now) {
+				m[k] = Item{Object: e.value, Expiration: e.expiration}
+			}
+		}
+		s.mu.Unlock()
+	}
+	return m
+}
+
+// Returns the number of items in the cache. This may include items that have
+// expired, but have not yet been cleaned up.
+func (c *boundedCache) ItemCount() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += len(s.items)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Returns the total size of the items in the cache, as counted against MaxBytes.
+func (c *boundedCache) Bytes() int64 {
+	var n int64
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += s.bytes
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Delete all items from the cache.
+func (c *boundedCache) Flush() {
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for _, e := range s.items {
+			s.remove(e)
+		}
+		s.mu.Unlock()
+	}
+}
+
+type boundedEntry struct {
+	key        string
+	hash       uint64
+	value      interface{}
+	expiration int64
+	size       int64
+
+	// bookkeeping of the policy
+	elem  *list.Element
+	freq  uint64
+	tick  uint64
+	index int
+}
+
+func (e *boundedEntry) expired(now int64) bool {
+	return e.expiration > 0 && now > e.expiration
+}
+
+const (
+	setAlways = iota
+	setIfAbsent
+	setIfPresent
+)
+
+type boundedShard struct {
+	mu         sync.Mutex
+	items      map[string]*boundedEntry
+	bytes      int64
+	maxEntries int64
+	maxBytes   int64
+	policy     evictionPolicy
+}
+
+func (s *boundedShard) full(extraEntries int64, extraBytes int64) bool {
+	return s.maxEntries > 0 && int64(len(s.items))+extraEntries > s.maxEntries ||
+		s.maxBytes > 0 && s.bytes+extraBytes > s.maxBytes
+}
+
+func (s *boundedShard) remove(e *boundedEntry) {
+	delete(s.items, e.key)
+	s.bytes -= e.size
+	s.policy.remove(e)
+}
+
+// Returns whether the item was stored and the items evicted for it.
+func (s *boundedShard) set(k string, h uint64, x interface{}, size int64, expiration int64, mode int) (bool, []keyAndValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy.record(h)
+	e, found := s.items[k]
+	if found && e.expired(time.Now().UnixNano()) {
+		s.remove(e)
+		found = false
+	}
+	if found && mode == setIfAbsent || !found && mode == setIfPresent {
+		return false, nil
+	}
+	if s.maxBytes > 0 && size > s.maxBytes {
+		// could never fit, do not flush the shard for it
+		if found {
+			s.remove(e)
+			return false, []keyAndValue{{k, e.value}}
+		}
+		return false, nil
+	}
+
+	var evicted []keyAndValue
+	if found {
+		s.bytes += size - e.size
+		e.value, e.size, e.expiration = x, size, expiration
+		s.policy.access(e)
+		for s.full(0, 0) {
+			victim := s.policy.victim()
+			if victim == e {
+				break
+			}
+			s.remove(victim)
+			evicted = append(evicted, keyAndValue{victim.key, victim.value})
+		}
+		return true, evicted
+	}
+
+	for s.full(1, size) && len(s.items) > 0 {
+		victim := s.policy.victim()
+		if !s.policy.admit(h, victim) {
+			return false, evicted
+		}
+		s.remove(victim)
+		evicted = append(evicted, keyAndValue{victim.key, victim.value})
+	}
+	e = &boundedEntry{key: k, hash: h, value: x, size: size, expiration: expiration}
+	s.items[k] = e
+	s.bytes += size
+	s.policy.add(e)
+	return true, evicted
+}
+
+// Keeps the order of eviction of the entries of a shard, called with the lock of the shard held.
+type evictionPolicy interface {
+	add(e *boundedEntry)
+	access(e *boundedEntry)
+	remove(e *boundedEntry)
+	// the next entry to evict
+	victim() *boundedEntry
+	// record a request of the key with hash h, whether it is in the cache or not
+	record(h uint64)
+	// whether the key with hash h is worth evicting victim
+	admit(h uint64, victim *boundedEntry) bool
+}
+
+type lruPolicy struct {
+	order *list.List // most recently used first
+}
+
+func newLRUPolicy() *lruPolicy {
+	return &lruPolicy{order: list.New()}
+}
+
+func (p *lruPolicy) add(e *boundedEntry) {
+	e.elem = p.order.PushFront(e)
+}
+
+func (p *lruPolicy) access(e *boundedEntry) {
+	p.order.MoveToFront(e.elem)
+}
+
+func (p *lruPolicy) remove(e *boundedEntry) {
+	p.order.Remove(e.elem)
+	e.elem = nil
+}
+
+func (p *lruPolicy) victim() *boundedEntry {
+	return p.order.Back().Value.(*boundedEntry)
+}
+
+func (p *lruPolicy) record(h uint64) {}
+
+func (p *lruPolicy) admit(h uint64, victim *boundedEntry) bool {
+	return true
+}
+
+// min-heap on (freq, tick)
+type lfuPolicy struct {
+	entries []*boundedEntry
+	ticks   uint64
+}
+
+func (p *lfuPolicy) Len() int {
+	return len(p.entries)
+}
+
+func (p *lfuPolicy) Less(i, j int) bool {
+	a, b := p.entries[i], p.entries[j]
+	if a.freq != b.freq {
+		return a.freq < b.freq
+	}
+	return a.tick < b.tick
+}
+
+func (p *lfuPolicy) Swap(i, j int) {
+	p.entries[i], p.entries[j] = p.entries[j], p.entries[i]
+	p.entries[i].index = i
+	p.entries[j].index = j
+}
+
+func (p *lfuPolicy) Push(x interface{}) {
+	e := x.(*boundedEntry)
+	e.index = len(p.entries)
+	p.entries = append(p.entries, e)
+}
+
+func (p *lfuPolicy) Pop() interface{} {
+	e := p.entries[len(p.entries)-1]
+	p.entries[len(p.entries)-1] = nil
+	p.entries = p.entries[:len(p.entries)-1]
+	return e
+}
+
+func (p *lfuPolicy) add(e *boundedEntry) {
+	p.ticks++
+	e.freq, e.tick = 1, p.ticks
+	heap.Push(p, e)
+}
+
+func (p *lfuPolicy) access(e *boundedEntry) {
+	p.ticks++
+	e.freq++
+	e.tick = p.ticks
+	heap.Fix(p, e.index)
+}
+
+func (p *lfuPolicy) remove(e *boundedEntry) {
+	heap.Remove(p, e.index)
+}
+
+func (p *lfuPolicy) victim() *boundedEntry {
+	return p.entries[0]
+}
+
+func (p *lfuPolicy) record(h uint64) {}
+
+func (p *lfuPolicy) admit(h uint64, victim *boundedEntry) bool {
+	return true
+}
+
+type tinyLFUPolicy struct {
+	*lruPolicy
+	sketch *countMinSketch
+}
+
+func newTinyLFUPolicy(capacity int) *tinyLFUPolicy {
+	return &tinyLFUPolicy{lruPolicy: newLRUPolicy(), sketch: newCountMinSketch(capacity)}
+}
+
+func (p *tinyLFUPolicy) record(h uint64) {
+	p.sketch.add(h)
+}
+
+func (p *tinyLFUPolicy) admit(h uint64, victim *boundedEntry) bool {
+	return p.sketch.estimate(h) > p.sketch.estimate(victim.hash)
+}
+
+// Approximate counts of the recent requests of the keys, in 4 rows of saturating 8 bits counters.
+// All the counters are halved every 10 * width requests so that old popularity fades away.
+type countMinSketch struct {
+	rows    [4][]uint8
+	mask    uint64
+	samples int
+	reset   int
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := 1024
+	for width < capacity {
+		width <<= 1
+	}
+	s := &countMinSketch{mask: uint64(width - 1), reset: 10 * width}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index of h in row i, by double hashing
+func (s *countMinSketch) index(h uint64, i int) uint64 {
+	return (h + uint64(i)*(h>>32|1)) & s.mask
+}
+
+func (s *countMinSketch) add(h uint64) {
+	for i := range s.rows {
+		if j := s.index(h, i); s.rows[i][j] < 255 {
+			s.rows[i][j]++
+		}
+	}
+	s.samples++
+	if s.samples >= s.reset {
+		for i := range s.rows {
+			for j := range s.rows[i] {
+				s.rows[i][j] >>= 1
+			}
+		}
+		s.samples /= 2
+	}
+}
+
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	min := uint8(255)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(h, i)]; v < min {
+			min = v
+		}
+	}
+	return min
+}
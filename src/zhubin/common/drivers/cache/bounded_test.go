@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBoundedCache_LRU(t *testing.T) {
+	c := NewBoundedCache(BoundedOptions{MaxEntries: 3, Policy: LRU})
+	var evicted []string
+	c.OnEvicted(func(k string, v interface{}) {
+		evicted = append(evicted, k)
+	})
+	c.Set("a", 1, NoExpiration)
+	c.Set("b", 2, NoExpiration)
+	c.Set("c", 3, NoExpiration)
+	c.Get("a")
+	c.Set("d", 4, NoExpiration)
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.Equal(t, 3, c.ItemCount())
+	_, found := c.Get("b")
+	assert.False(t, found)
+	v, found := c.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, 1, v)
+
+	// overwriting does not evict
+	c.Set("a", 10, NoExpiration)
+	assert.Equal(t, []string{"b"}, evicted)
+	c.Delete("a")
+	assert.Equal(t, []string{"b", "a"}, evicted)
+
+	assert.Nil(t, c.Add("e", 5, NoExpiration))
+	assert.NotNil(t, c.Add("e", 5, NoExpiration))
+	assert.NotNil(t, c.Replace("missing", 5, NoExpiration))
+}
+
+func TestBoundedCache_LFU(t *testing.T) {
+	c := NewBoundedCache(BoundedOptions{MaxEntries: 3, Policy: LFU})
+	c.Set("a", 1, NoExpiration)
+	c.Set("b", 2, NoExpiration)
+	c.Set("c", 3, NoExpiration)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Get("c")
+	c.Get("c")
+	c.Set("d", 4, NoExpiration)
+	_, found := c.Get("b")
+	assert.False(t, found)
+	for _, k := range []string{"a", "c", "d"} {
+		_, found := c.Get(k)
+		assert.True(t, found, k)
+	}
+}
+
+func TestBoundedCache_TinyLFU(t *testing.T) {
+	c := NewBoundedCache(BoundedOptions{MaxEntries: 100, Policy: TinyLFU})
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprintf("hot%d", i)
+		c.Set(k, i, NoExpiration)
+		for j := 0; j < 5; j++ {
+			c.Get(k)
+		}
+	}
+	// a flood of keys requested once does not push the hot ones out
+	for i := 0; i < 1000; i++ {
+		c.Set(fmt.Sprintf("cold%d", i), i, NoExpiration)
+	}
+	hits := 0
+	for i := 0; i < 100; i++ {
+		if _, found := c.Get(fmt.Sprintf("hot%d", i)); found {
+			hits++
+		}
+	}
+	assert.True(t, hits > 90, "hits: %d", hits)
+	assert.True(t, c.ItemCount() <= 100)
+}
+
+func TestBoundedCache_MaxBytes(t *testing.T) {
+	c := NewBoundedCache(BoundedOptions{MaxBytes: 100, Shards: 1})
+	c.Set("a", string(make([]byte, 39)), NoExpiration)
+	c.Set("b", string(make([]byte, 39)), NoExpiration)
+	assert.Equal(t, int64(80), c.Bytes())
+	c.Set("c", string(make([]byte, 39)), NoExpiration)
+	assert.Equal(t, int64(80), c.Bytes())
+	_, found := c.Get("a")
+	assert.False(t, found)
+
+	// too large to ever fit
+	c.Set("huge", string(make([]byte, 200)), NoExpiration)
+	_, found = c.Get("huge")
+	assert.False(t, found)
+	assert.Equal(t, 2, c.ItemCount())
+}
+
+func TestBoundedCache_Expiration(t *testing.T) {
+	c := NewBoundedCache(BoundedOptions{DefaultExpiration: 20 * time.Millisecond, CleanupInterval: 10 * time.Millisecond})
+	var mu sync.Mutex
+	var evicted []string
+	c.OnEvicted(func(k string, v interface{}) {
+		mu.Lock()
+		evicted = append(evicted, k)
+		mu.Unlock()
+	})
+	c.SetDefault("a", 1)
+	c.Set("b", 2, NoExpiration)
+	time.Sleep(50 * time.Millisecond)
+	_, found := c.Get("a")
+	assert.False(t, found)
+	assert.Equal(t, map[string]Item{"b": {Object: 2}}, c.Items())
+	mu.Lock()
+	assert.Equal(t, []string{"a"}, evicted)
+	mu.Unlock()
+}
+
+func TestBoundedCache_Concurrent(t *testing.T) {
+	c := NewBoundedCache(BoundedOptions{MaxEntries: 1000, Policy: TinyLFU})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				k := fmt.Sprintf("%d-%d", g, i%300)
+				c.Set(k, i, NoExpiration)
+				c.Get(k)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.True(t, c.ItemCount() <= 1000+len(c.shards))
+}
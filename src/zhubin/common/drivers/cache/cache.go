@@ -2,30 +2,52 @@ package cache
 
 import (
 	"errors"
+	"io"
+	"time"
 )
 
 var ErrKeyNotFound = errors.New("key not found")
 
 type L2Cache interface {
 	// Returns all keys matching pattern.
+	// Iterates with SCAN so that the server is not blocked, on cluster all the masters are scanned.
 	Keys(pattern string) ([]string, error)
 
+	// Iterates over the keys matching pattern with SCAN, on cluster all the masters are scanned one after the other.
+	// count is a hint of the number of keys fetched per round trip, <= 0 for the default of the server.
+	// NOTE keys added or removed during the iteration may or may not be returned, and a key may be returned twice.
+	Scan(pattern string, count int) KeyIterator
+
+	// Deletes the keys matching pattern, return the number of keys deleted.
+	DeleteByPattern(pattern string) (int64, error)
+
 	// Get fetches value by given key from cache.
 	// Should report ErrKeyNotFound when no associated value found
 	Get(key string) (value string, err error)
-	//GetObject(key string) (value interface{}, err errors)
+
+	// GetObject decodes the value saved by SaveObject into out, which must be a pointer.
+	// Should report ErrKeyNotFound when no associated value found
+	GetObject(key string, out interface{}) error
 
 	// Save stores the key and corresponding value in Redis.
 	// if exp_time_seconds <= 0, the key never expire
 	Save(key string, value string, exp_time_seconds int) error
 
 	// Save stores the key， value is inerface{}
+	// value is encoded with the codec of the client, except strings and byte slices which are stored as they are.
 	SaveObject(key string, value interface{}, exp_time_seconds int) error
 
 	// Set key to hold string value if key does not exist.
 	SaveIfNotExists(key string, value string, exp_time_seconds int) error
 	SaveObjectIfNotExists(key string, value interface{}, exp_time_seconds int) error
 
+	// Like SaveIfNotExists, but reports whether the key was set. ttl <= 0 means the key never expire.
+	TrySave(key string, value string, ttl time.Duration) (bool, error)
+
+	// Runs a lua script on the server holding the keys, which must all hash to the same slot on cluster.
+	// Integer replies are returned as int64, nil replies as nil, status and bulk strings as string.
+	Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+
 	// Check if the given key exists.
 	Exists(key string) (bool, error)
 
@@ -41,6 +63,101 @@ type L2Cache interface {
 	// Delete removes one or more keys.
 	Delete(key ...string) error
 
+	// Batches, in one round trip on standalone and one per node on cluster.
+	// MGet returns the values of the keys found.
+	MGet(key ...string) (map[string]string, error)
+	// if exp_time_seconds <= 0, the keys never expire
+	MSave(values map[string]string, exp_time_seconds int) error
+	// Removes the keys, which may be in different slots on cluster, return the number of keys removed.
+	MDelete(key ...string) (int64, error)
+
+	// Commands sent together, see Pipeline.
+	Pipeline() Pipeline
+	// Commands run atomically with MULTI/EXEC, on cluster their keys must all be in the same slot.
+	TxPipeline() Pipeline
+
+	// Hashes.
+	// HGet reports ErrKeyNotFound when the key or the field does not exist.
+	HGet(key string, field string) (string, error)
+	HSet(key string, field string, value string) error
+	// Returns an empty map if the key does not exist.
+	HGetAll(key string) (map[string]string, error)
+	// Increments the number stored at field by incValue, return the value after increment.
+	HIncrBy(key string, field string, incValue int64) (int64, error)
+	HDel(key string, field ...string) error
+
+	// Lists.
+	// Prepends the values, return the length of the list after the push.
+	LPush(key string, value ...string) (int64, error)
+	// Removes and returns the last element, reports ErrKeyNotFound when the list is empty.
+	RPop(key string) (string, error)
+	// Elements from start to stop included, negative indexes count from the end, e.g. -1 is the last element.
+	LRange(key string, start int64, stop int64) ([]string, error)
+	LLen(key string) (int64, error)
+
+	// Sets.
+	// Returns the number of members actually added.
+	SAdd(key string, member ...string) (int64, error)
+	SRem(key string, member ...string) error
+	SMembers(key string) ([]string, error)
+	SIsMember(key string, member string) (bool, error)
+
+	// Sorted sets.
+	// Adds the members or updates their scores, returns the number of members actually added.
+	ZAdd(key string, member ...Z) (int64, error)
+	// Increments the score of member by incValue, return the score after increment.
+	ZIncrBy(key string, member string, incValue float64) (float64, error)
+	// Members with a score between min and max, lowest first, e.g. "-inf", "+inf" or "(10" to exclude 10.
+	// Skips offset members and returns at most count of them, all of them if count <= 0.
+	ZRangeByScore(key string, min string, max string, offset int64, count int64) ([]Z, error)
+	// Rank of member ordered from the highest score, 0 being the first, reports ErrKeyNotFound when there is no such member.
+	ZRevRank(key string, member string) (int64, error)
+	ZRem(key string, member ...string) error
+
 	// close the cache client
 	Close() error
-}
\ No newline at end of file
+}
+
+// Member of a sorted set.
+type Z struct {
+	Score  float64
+	Member string
+}
+
+// Cursor over keys:
+//
+//	it := client.Scan("session:*", 100)
+//	defer it.Close()
+//	for it.Next() {
+//		key := it.Key()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type KeyIterator interface {
+	// Advances to the next key, returns false when there are no more keys or on error.
+	Next() bool
+	Key() string
+	Err() error
+	// Releases the connections held, needed only if the iteration is stopped before Next returns false.
+	Close() error
+}
+
+func toInterfaces(ss []string) []interface{} {
+	ret := make([]interface{}, len(ss))
+	for i, s := range ss {
+		ret[i] = s
+	}
+	return ret
+}
+
+// Publish/subscribe of the L2 cache, e.g. to tell other instances that a key changed.
+type PubSub interface {
+	// Publish message to all the subscribers of channel.
+	Publish(channel string, message string) error
+
+	// Subscribe calls handler with every message published to channel, in a goroutine of its own,
+	// until the returned io.Closer is closed.
+	Subscribe(channel string, handler func(message string)) (io.Closer, error)
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"bytes"
+	"datamesh.com/common/utils/compress"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/ugorji/go/codec"
+)
+
+var ErrNotProtoMessage = errors.New("value is not a proto.Message")
+
+// Serializes the values of SaveObject and GetObject.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	// v must be a pointer
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+	GobCodec     Codec = gobCodec{}
+	// values must implement proto.Message
+	ProtobufCodec Codec = protobufCodec{}
+)
+
+// Codec by name, as found in the cache configuration: json, msgpack, gob or protobuf.
+// The default one is json.
+func NewCodec(name string, gzip bool) (Codec, error) {
+	var c Codec
+	switch name {
+	case "", "json":
+		c = JSONCodec
+	case "msgpack":
+		c = MsgpackCodec
+	case "gob":
+		c = GobCodec
+	case "protobuf":
+		c = ProtobufCodec
+	default:
+		return nil, fmt.Errorf("unknown cache codec: %s", name)
+	}
+	if gzip {
+		c = GzipCodec(c)
+	}
+	return c, nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+var msgpackHandle = &codec.MsgpackHandle{RawToString: true}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, msgpackHandle).Encode(v)
+	return data, err
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, msgpackHandle).Decode(v)
+}
+
+// NOTE values held in interfaces must be registered with gob.Register.
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// Compresses the output of c with gzip, worth it for large values only.
+func GzipCodec(c Codec) Codec {
+	return gzipCodec{c}
+}
+
+type gzipCodec struct {
+	codec Codec
+}
+
+func (g gzipCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := g.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return compress.GZEncode(data)
+}
+
+func (g gzipCodec) Unmarshal(data []byte, v interface{}) error {
+	data, err := compress.GZDecode(data)
+	if err != nil {
+		return err
+	}
+	return g.codec.Unmarshal(data, v)
+}
+
+// Strings and byte slices are stored as they are, so that they can be read back with Get,
+// everything else is encoded with c, JSONCodec if nil.
+func encodeObject(c Codec, value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case string, []byte:
+		return value, nil
+	}
+	if c == nil {
+		c = JSONCodec
+	}
+	return c.Marshal(value)
+}
+
+func decodeObject(c Codec, data string, out interface{}) error {
+	switch out := out.(type) {
+	case *string:
+		*out = data
+		return nil
+	case *[]byte:
+		*out = []byte(data)
+		return nil
+	}
+	if c == nil {
+		c = JSONCodec
+	}
+	return c.Unmarshal([]byte(data), out)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"github.com/alicebob/miniredis"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type codecObject struct {
+	Name  string
+	Count int
+	Tags  []string
+}
+
+func TestCodec(t *testing.T) {
+	in := codecObject{Name: "object", Count: 3, Tags: []string{"a", "b"}}
+	for _, name := range []string{"json", "msgpack", "gob"} {
+		for _, gzip := range []bool{false, true} {
+			c, err := NewCodec(name, gzip)
+			assert.Nil(t, err)
+			data, err := c.Marshal(in)
+			assert.Nil(t, err, name)
+			var out codecObject
+			assert.Nil(t, c.Unmarshal(data, &out), name)
+			assert.Equal(t, in, out, name)
+		}
+	}
+
+	c, err := NewCodec("protobuf", true)
+	assert.Nil(t, err)
+	data, err := c.Marshal(&wrappers.StringValue{Value: "object"})
+	assert.Nil(t, err)
+	var out wrappers.StringValue
+	assert.Nil(t, c.Unmarshal(data, &out))
+	assert.Equal(t, "object", out.Value)
+	_, err = c.Marshal(in)
+	assert.Equal(t, ErrNotProtoMessage, err)
+
+	_, err = NewCodec("xml", false)
+	assert.NotNil(t, err)
+}
+
+func TestRedis_GetObject(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	client := NewRedis(s.Addr(), "", 0)
+	defer client.Close()
+
+	in := codecObject{Name: "object", Count: 3}
+	assert.Nil(t, client.SaveObject("json", in, 0))
+	raw, err := client.Get("json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Name":"object","Count":3,"Tags":null}`, raw)
+	var out codecObject
+	assert.Nil(t, client.GetObject("json", &out))
+	assert.Equal(t, in, out)
+
+	client.Codec = GzipCodec(MsgpackCodec)
+	assert.Nil(t, client.SaveObject("msgpack", in, 0))
+	out = codecObject{}
+	assert.Nil(t, client.GetObject("msgpack", &out))
+	assert.Equal(t, in, out)
+
+	client.Codec = ProtobufCodec
+	assert.Nil(t, client.SaveObject("protobuf", &wrappers.Int64Value{Value: 42}, 0))
+	var pb wrappers.Int64Value
+	assert.Nil(t, client.GetObject("protobuf", &pb))
+	assert.True(t, proto.Equal(&wrappers.Int64Value{Value: 42}, &pb))
+
+	// strings are stored as they are
+	assert.Nil(t, client.SaveObject("string", "plain", 0))
+	raw, err = client.Get("string")
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", raw)
+	var str string
+	assert.Nil(t, client.GetObject("string", &str))
+	assert.Equal(t, "plain", str)
+
+	assert.Equal(t, ErrKeyNotFound, client.GetObject("missing", &out))
+}
@@ -1,8 +1,23 @@
 package cache
 
 type Cache struct {
-	Address   string `yaml:"address"` // host:port
+	Address   string `yaml:"address"` // host:port, not applicable on sentinel
 	Password  string `yaml:"password"`
 	DbNum     int    `yaml:"db_num"` // not applicable on cluster
 	IsCluster bool   `yaml:"is_cluster"`
+	Codec     string `yaml:"codec"` // json (default), msgpack, gob or protobuf
+	Gzip      bool   `yaml:"gzip"`  // compress the encoded objects
+
+	IsSentinel      bool     `yaml:"is_sentinel"`
+	MasterName      string   `yaml:"master_name"`
+	SentinelAddrs   []string `yaml:"sentinel_addrs"` // host:port of the sentinels
+	ReadFromReplica bool     `yaml:"read_from_replica"`
+
+	Metrics   bool `yaml:"metrics"`     // record the metrics in L2_CACHE_METRICS
+	SlowLogMs int  `yaml:"slow_log_ms"` // log the commands slower than this, 0 disables
+
+	// Do not panic when redis is down, see ResilientCache.
+	Resilient       bool `yaml:"resilient"`
+	Fallback        bool `yaml:"fallback"`         // serve from an in-process cache while redis is unavailable
+	FallbackEntries int  `yaml:"fallback_entries"` // limit of the fallback cache, 0 means no limit
 }
\ No newline at end of file
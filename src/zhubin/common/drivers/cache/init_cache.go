@@ -1,11 +1,93 @@
 package cache
 
+import (
+	"time"
+)
+
 var L2_CACHE_CLIENT L2Cache
 
+// Set in resilient mode, e.g. to check its Health.
+var L2_CACHE_RESILIENT *ResilientCache
+
+// Panics if redis can not be reached, unless cacheConfig.Resilient.
 func InitL2CacheClient(cacheConfig *Cache) {
+	if cacheConfig.Resilient {
+		L2_CACHE_RESILIENT = newResilientL2CacheClient(cacheConfig)
+		L2_CACHE_CLIENT = L2_CACHE_RESILIENT
+	} else {
+		L2_CACHE_CLIENT = newL2CacheClient(cacheConfig)
+	}
+	if cacheConfig.Metrics || cacheConfig.SlowLogMs > 0 {
+		L2_CACHE_METRICS.SlowThreshold = time.Duration(cacheConfig.SlowLogMs) * time.Millisecond
+		L2_CACHE_CLIENT = NewInstrumentedCache("l2", L2_CACHE_CLIENT, L2_CACHE_METRICS)
+	}
+}
+
+func newL2CacheClient(cacheConfig *Cache) L2Cache {
+	codec, err := NewCodec(cacheConfig.Codec, cacheConfig.Gzip)
+	if err != nil {
+		panic(err)
+	}
+	if cacheConfig.IsCluster {
+		cluster := NewRedisCluster(cacheConfig.Address, cacheConfig.Password)
+		cluster.Codec = codec
+		return cluster
+	}
+	if cacheConfig.IsSentinel {
+		client := NewRedisSentinel(cacheConfig.MasterName, cacheConfig.SentinelAddrs, cacheConfig.Password, cacheConfig.DbNum, cacheConfig.ReadFromReplica)
+		client.Codec = codec
+		return client
+	}
+	client := NewRedis(cacheConfig.Address, cacheConfig.Password, cacheConfig.DbNum)
+	client.Codec = codec
+	return client
+}
+
+// how long the values stay in the fallback cache at most, so that they are not too stale
+const fallbackExpiration = 5 * time.Minute
+
+// Connects on the first command, an invalid codec still panics.
+func newResilientL2CacheClient(cacheConfig *Cache) *ResilientCache {
+	codec, err := NewCodec(cacheConfig.Codec, cacheConfig.Gzip)
+	if err != nil {
+		panic(err)
+	}
+	var fallback LocalCache
+	if cacheConfig.Fallback {
+		if cacheConfig.FallbackEntries > 0 {
+			fallback = NewBoundedCache(BoundedOptions{
+				DefaultExpiration: fallbackExpiration,
+				CleanupInterval:   time.Minute,
+				MaxEntries:        cacheConfig.FallbackEntries,
+			})
+		} else {
+			fallback = NewCache(fallbackExpiration, time.Minute)
+		}
+	}
+	return NewResilientCache(func() (L2Cache, error) {
+		return dialL2CacheClient(cacheConfig, codec)
+	}, fallback)
+}
+
+func dialL2CacheClient(cacheConfig *Cache, codec Codec) (L2Cache, error) {
 	if cacheConfig.IsCluster {
-		L2_CACHE_CLIENT = NewRedisCluster(cacheConfig.Address, cacheConfig.Password)
-		return
+		cluster, err := DialRedisCluster(cacheConfig.Address, cacheConfig.Password)
+		if err != nil {
+			return nil, err
+		}
+		cluster.Codec = codec
+		return cluster, nil
+	}
+	var client *Redis
+	var err error
+	if cacheConfig.IsSentinel {
+		client, err = DialRedisSentinel(cacheConfig.MasterName, cacheConfig.SentinelAddrs, cacheConfig.Password, cacheConfig.DbNum, cacheConfig.ReadFromReplica)
+	} else {
+		client, err = DialRedis(cacheConfig.Address, cacheConfig.Password, cacheConfig.DbNum)
+	}
+	if err != nil {
+		return nil, err
 	}
-	L2_CACHE_CLIENT = NewRedis(cacheConfig.Address, cacheConfig.Password, cacheConfig.DbNum)
+	client.Codec = codec
+	return client, nil
 }
\ No newline at end of file
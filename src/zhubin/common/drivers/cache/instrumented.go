@@ -0,0 +1,380 @@
+package cache
+
+import (
+	"io"
+	"time"
+)
+
+// Wraps c to record the metrics of its operations under name, e.g.
+//
+//	L2_CACHE_CLIENT = cache.NewInstrumentedCache("l2", L2_CACHE_CLIENT, cache.L2_CACHE_METRICS)
+//
+// The result implements PubSub if c does.
+func NewInstrumentedCache(name string, c L2Cache, metrics *Metrics) L2Cache {
+	if c == nil || metrics == nil {
+		panic("Uninitialized cache.")
+	}
+	ic := &instrumentedCache{name: name, cache: c, metrics: metrics}
+	if ps, ok := c.(PubSub); ok {
+		return &instrumentedPubSubCache{instrumentedCache: ic, pubsub: ps}
+	}
+	return ic
+}
+
+type instrumentedCache struct {
+	name    string
+	cache   L2Cache
+	metrics *Metrics
+}
+
+func firstKey(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
+
+// Only the creation of the iterator is recorded.
+func (c *instrumentedCache) Scan(pattern string, count int) KeyIterator {
+	begin := time.Now()
+	it := c.cache.Scan(pattern, count)
+	c.metrics.observe(c.name, "scan", pattern, begin, false, nil)
+	return it
+}
+
+func (c *instrumentedCache) MGet(key ...string) (map[string]string, error) {
+	begin := time.Now()
+	ret, err := c.cache.MGet(key...)
+	c.metrics.observe(c.name, "mget", firstKey(key), begin, false, err)
+	if err == nil {
+		c.metrics.lookups(c.name, "mget", len(ret), len(key)-len(ret))
+	}
+	return ret, err
+}
+
+func (c *instrumentedCache) Pipeline() Pipeline {
+	return &instrumentedPipeline{c: c, op: "pipeline", pipeline: c.cache.Pipeline()}
+}
+
+func (c *instrumentedCache) TxPipeline() Pipeline {
+	return &instrumentedPipeline{c: c, op: "txpipeline", pipeline: c.cache.TxPipeline()}
+}
+
+func (c *instrumentedCache) Close() error {
+	return c.cache.Close()
+}
+
+func (c *instrumentedCache) Keys(pattern string) ([]string, error) {
+	begin := time.Now()
+	ret, err := c.cache.Keys(pattern)
+	c.metrics.observe(c.name, "keys", pattern, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) DeleteByPattern(pattern string) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.DeleteByPattern(pattern)
+	c.metrics.observe(c.name, "deletebypattern", pattern, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) Get(key string) (string, error) {
+	begin := time.Now()
+	ret, err := c.cache.Get(key)
+	c.metrics.observe(c.name, "get", key, begin, true, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) GetObject(key string, out interface{}) error {
+	begin := time.Now()
+	err := c.cache.GetObject(key, out)
+	c.metrics.observe(c.name, "getobject", key, begin, true, err)
+	return err
+}
+
+func (c *instrumentedCache) Save(key string, value string, exp_time_seconds int) error {
+	begin := time.Now()
+	err := c.cache.Save(key, value, exp_time_seconds)
+	c.metrics.observe(c.name, "save", key, begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) SaveObject(key string, value interface{}, exp_time_seconds int) error {
+	begin := time.Now()
+	err := c.cache.SaveObject(key, value, exp_time_seconds)
+	c.metrics.observe(c.name, "saveobject", key, begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) SaveIfNotExists(key string, value string, exp_time_seconds int) error {
+	begin := time.Now()
+	err := c.cache.SaveIfNotExists(key, value, exp_time_seconds)
+	c.metrics.observe(c.name, "saveifnotexists", key, begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) SaveObjectIfNotExists(key string, value interface{}, exp_time_seconds int) error {
+	begin := time.Now()
+	err := c.cache.SaveObjectIfNotExists(key, value, exp_time_seconds)
+	c.metrics.observe(c.name, "saveobjectifnotexists", key, begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) TrySave(key string, value string, ttl time.Duration) (bool, error) {
+	begin := time.Now()
+	ret, err := c.cache.TrySave(key, value, ttl)
+	c.metrics.observe(c.name, "trysave", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	begin := time.Now()
+	ret, err := c.cache.Eval(script, keys, args...)
+	c.metrics.observe(c.name, "eval", firstKey(keys), begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) Exists(key string) (bool, error) {
+	begin := time.Now()
+	ret, err := c.cache.Exists(key)
+	c.metrics.observe(c.name, "exists", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) SetExpire(key string, exp_time_seconds int) error {
+	begin := time.Now()
+	err := c.cache.SetExpire(key, exp_time_seconds)
+	c.metrics.observe(c.name, "setexpire", key, begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) Incr(key string) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.Incr(key)
+	c.metrics.observe(c.name, "incr", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) IncrBy(key string, incValue int64) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.IncrBy(key, incValue)
+	c.metrics.observe(c.name, "incrby", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) Delete(key ...string) error {
+	begin := time.Now()
+	err := c.cache.Delete(key...)
+	c.metrics.observe(c.name, "delete", firstKey(key), begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) MSave(values map[string]string, exp_time_seconds int) error {
+	begin := time.Now()
+	err := c.cache.MSave(values, exp_time_seconds)
+	c.metrics.observe(c.name, "msave", "", begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) MDelete(key ...string) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.MDelete(key...)
+	c.metrics.observe(c.name, "mdelete", firstKey(key), begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) HGet(key string, field string) (string, error) {
+	begin := time.Now()
+	ret, err := c.cache.HGet(key, field)
+	c.metrics.observe(c.name, "hget", key, begin, true, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) HSet(key string, field string, value string) error {
+	begin := time.Now()
+	err := c.cache.HSet(key, field, value)
+	c.metrics.observe(c.name, "hset", key, begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) HGetAll(key string) (map[string]string, error) {
+	begin := time.Now()
+	ret, err := c.cache.HGetAll(key)
+	c.metrics.observe(c.name, "hgetall", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) HIncrBy(key string, field string, incValue int64) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.HIncrBy(key, field, incValue)
+	c.metrics.observe(c.name, "hincrby", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) HDel(key string, field ...string) error {
+	begin := time.Now()
+	err := c.cache.HDel(key, field...)
+	c.metrics.observe(c.name, "hdel", key, begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) LPush(key string, value ...string) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.LPush(key, value...)
+	c.metrics.observe(c.name, "lpush", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) RPop(key string) (string, error) {
+	begin := time.Now()
+	ret, err := c.cache.RPop(key)
+	c.metrics.observe(c.name, "rpop", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) LRange(key string, start int64, stop int64) ([]string, error) {
+	begin := time.Now()
+	ret, err := c.cache.LRange(key, start, stop)
+	c.metrics.observe(c.name, "lrange", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) LLen(key string) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.LLen(key)
+	c.metrics.observe(c.name, "llen", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) SAdd(key string, member ...string) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.SAdd(key, member...)
+	c.metrics.observe(c.name, "sadd", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) SRem(key string, member ...string) error {
+	begin := time.Now()
+	err := c.cache.SRem(key, member...)
+	c.metrics.observe(c.name, "srem", key, begin, false, err)
+	return err
+}
+
+func (c *instrumentedCache) SMembers(key string) ([]string, error) {
+	begin := time.Now()
+	ret, err := c.cache.SMembers(key)
+	c.metrics.observe(c.name, "smembers", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) SIsMember(key string, member string) (bool, error) {
+	begin := time.Now()
+	ret, err := c.cache.SIsMember(key, member)
+	c.metrics.observe(c.name, "sismember", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) ZAdd(key string, member ...Z) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.ZAdd(key, member...)
+	c.metrics.observe(c.name, "zadd", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) ZIncrBy(key string, member string, incValue float64) (float64, error) {
+	begin := time.Now()
+	ret, err := c.cache.ZIncrBy(key, member, incValue)
+	c.metrics.observe(c.name, "zincrby", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) ZRangeByScore(key string, min string, max string, offset int64, count int64) ([]Z, error) {
+	begin := time.Now()
+	ret, err := c.cache.ZRangeByScore(key, min, max, offset, count)
+	c.metrics.observe(c.name, "zrangebyscore", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) ZRevRank(key string, member string) (int64, error) {
+	begin := time.Now()
+	ret, err := c.cache.ZRevRank(key, member)
+	c.metrics.observe(c.name, "zrevrank", key, begin, false, err)
+	return ret, err
+}
+
+func (c *instrumentedCache) ZRem(key string, member ...string) error {
+	begin := time.Now()
+	err := c.cache.ZRem(key, member...)
+	c.metrics.observe(c.name, "zrem", key, begin, false, err)
+	return err
+}
+
+type instrumentedPipeline struct {
+	c        *instrumentedCache
+	op       string
+	pipeline Pipeline
+}
+
+func (p *instrumentedPipeline) Cmd(args ...interface{}) {
+	p.pipeline.Cmd(args...)
+}
+
+func (p *instrumentedPipeline) Exec() ([]interface{}, error) {
+	begin := time.Now()
+	ret, err := p.pipeline.Exec()
+	p.c.metrics.observe(p.c.name, p.op, "", begin, false, err)
+	return ret, err
+}
+
+type instrumentedPubSubCache struct {
+	*instrumentedCache
+	pubsub PubSub
+}
+
+func (c *instrumentedPubSubCache) Publish(channel string, message string) error {
+	begin := time.Now()
+	err := c.pubsub.Publish(channel, message)
+	c.metrics.observe(c.name, "publish", channel, begin, false, err)
+	return err
+}
+
+func (c *instrumentedPubSubCache) Subscribe(channel string, handler func(message string)) (io.Closer, error) {
+	return c.pubsub.Subscribe(channel, handler)
+}
+
+// L1Cache recording the metrics of Get, Set and Delete under name.
+type InstrumentedL1Cache struct {
+	*L1Cache
+	name    string
+	metrics *Metrics
+}
+
+func NewInstrumentedL1Cache(name string, c *L1Cache, metrics *Metrics) *InstrumentedL1Cache {
+	if c == nil || metrics == nil {
+		panic("Uninitialized cache.")
+	}
+	return &InstrumentedL1Cache{L1Cache: c, name: name, metrics: metrics}
+}
+
+func (c *InstrumentedL1Cache) Get(k string) (interface{}, bool) {
+	begin := time.Now()
+	v, found := c.L1Cache.Get(k)
+	var err error
+	if !found {
+		err = ErrKeyNotFound
+	}
+	c.metrics.observe(c.name, "get", k, begin, true, err)
+	return v, found
+}
+
+func (c *InstrumentedL1Cache) Set(k string, x interface{}, d time.Duration) {
+	begin := time.Now()
+	c.L1Cache.Set(k, x, d)
+	c.metrics.observe(c.name, "set", k, begin, false, nil)
+}
+
+func (c *InstrumentedL1Cache) Delete(k string) {
+	begin := time.Now()
+	c.L1Cache.Delete(k)
+	c.metrics.observe(c.name, "delete", k, begin, false, nil)
+}
@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"datamesh.com/common/utils/randgen"
+	"github.com/sirupsen/logrus"
+	"hash/fnv"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Loads the value of key from the source of truth when it is in neither cache level.
+// Should report ErrKeyNotFound when there is no such value, which is then cached as well.
+type Loader func(key string) (value string, err error)
+
+// Read-through cache checking the in-process L1 first, then the shared L2, then the loader.
+// Concurrent misses of the same key in one process share a single L2 lookup and load.
+// When the L2 cache supports PubSub, the keys written or deleted by one instance
+// are dropped from the L1 of the others.
+type LayeredCache struct {
+	// How long a value stays in L1, 0 means the default expiration of the L1Cache.
+	L1Expiration time.Duration
+	// How long a loaded value stays in L2, <= 0 means it never expires.
+	L2ExpirationSeconds int
+	// How long a key reported not found by the loader stays in L1, 0 disables the negative cache.
+	// NOTE it is only cached in L1, other instances call the loader once on their own.
+	NegativeExpiration time.Duration
+
+	l1      LocalCache
+	l2      L2Cache
+	id      string
+	channel string
+	sub     io.Closer
+	flight  flightGroup
+
+	// Generations of the keys, hashed into stripes and bumped by every invalidation,
+	// so that a value read before an invalidation is not stored in L1 after it.
+	genMu sync.Mutex
+	gens  [layeredGenStripes]uint64
+}
+
+const layeredGenStripes = 256
+
+// value cached in L1 for the keys not found
+type notFound struct{}
+
+// l1 is usually a L1Cache, or a BoundedL1Cache to limit the memory used.
+// If channel is not empty and l2 implements PubSub, invalidations are broadcast on it.
+// The instances sharing a L2 cache must use the same channel.
+func NewLayeredCache(l1 LocalCache, l2 L2Cache, channel string) (*LayeredCache, error) {
+	if l1 == nil || l2 == nil {
+		panic("Uninitialized cache.")
+	}
+	c := &LayeredCache{l1: l1, l2: l2, id: randgen.GenUUID(), channel: channel}
+	if ps, ok := l2.(PubSub); ok && channel != "" {
+		sub, err := ps.Subscribe(channel, c.onInvalidate)
+		if err != nil {
+			return nil, err
+		}
+		c.sub = sub
+	}
+	return c, nil
+}
+
+// Get fetches value by given key, filling the cache levels it was missing from.
+// loader may be nil, then only the caches are checked.
+func (c *LayeredCache) Get(key string, loader Loader) (string, error) {
+	if v, found := c.l1.Get(key); found {
+		if _, ok := v.(notFound); ok {
+			return "", ErrKeyNotFound
+		}
+		return v.(string), nil
+	}
+	return c.flight.Do(key, func() (string, error) {
+		gen := c.generation(key)
+		value, err := c.l2.Get(key)
+		if err == nil {
+			c.setL1(key, gen, value, c.L1Expiration)
+			return value, nil
+		}
+		if err != ErrKeyNotFound || loader == nil {
+			return "", err
+		}
+		value, err = loader(key)
+		if err == ErrKeyNotFound {
+			if c.NegativeExpiration > 0 {
+				c.setL1(key, gen, notFound{}, c.NegativeExpiration)
+			}
+			return "", err
+		}
+		if err != nil {
+			return "", err
+		}
+		// the value may be older than one stored meanwhile by another instance, which is kept
+		saved, err := c.l2.TrySave(key, value, time.Duration(c.L2ExpirationSeconds)*time.Second)
+		if err != nil {
+			return "", err
+		}
+		if !saved {
+			if stored, err := c.l2.Get(key); err == nil {
+				value = stored
+			}
+		} else if c.generation(key) != gen {
+			// invalidated while loading, the value may be older than the source of truth
+			if err := c.l2.Delete(key); err != nil {
+				return "", err
+			}
+		}
+		c.setL1(key, gen, value, c.L1Expiration)
+		return value, nil
+	})
+}
+
+// Save stores the value in both levels and invalidates the other instances.
+func (c *LayeredCache) Save(key string, value string, exp_time_seconds int) error {
+	gen := c.generation(key)
+	if err := c.l2.Save(key, value, exp_time_seconds); err != nil {
+		return err
+	}
+	c.setL1(key, gen, value, c.L1Expiration)
+	return c.publish(key)
+}
+
+// Delete removes the keys from both levels and invalidates the other instances.
+func (c *LayeredCache) Delete(key ...string) error {
+	if err := c.l2.Delete(key...); err != nil {
+		return err
+	}
+	for _, k := range key {
+		c.dropL1(k)
+	}
+	return c.publish(key...)
+}
+
+// Invalidate drops the keys from both levels after the source of truth changed,
+// so that the next Get calls the loader. Values being loaded meanwhile are not cached.
+func (c *LayeredCache) Invalidate(key ...string) error {
+	return c.Delete(key...)
+}
+
+// Stops listening to invalidations, the L1 and L2 caches are not closed.
+func (c *LayeredCache) Close() error {
+	if c.sub == nil {
+		return nil
+	}
+	return c.sub.Close()
+}
+
+// message format: "<instance id> <key>"
+func (c *LayeredCache) publish(key ...string) error {
+	ps, ok := c.l2.(PubSub)
+	if !ok || c.channel == "" {
+		return nil
+	}
+	for _, k := range key {
+		if err := ps.Publish(c.channel, c.id+" "+k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *LayeredCache) onInvalidate(message string) {
+	parts := strings.SplitN(message, " ", 2)
+	if len(parts) != 2 {
+		logrus.Warnf("layered cache: malformed invalidation %q", message)
+		return
+	}
+	if parts[0] == c.id {
+		return
+	}
+	c.dropL1(parts[1])
+}
+
+func (c *LayeredCache) stripe(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % layeredGenStripes)
+}
+
+// the generation of key, to pass to setL1 once its value is read
+func (c *LayeredCache) generation(key string) uint64 {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	return c.gens[c.stripe(key)]
+}
+
+// store value in L1 unless key was invalidated since gen
+func (c *LayeredCache) setL1(key string, gen uint64, value interface{}, expiration time.Duration) {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	if c.gens[c.stripe(key)] == gen {
+		c.l1.Set(key, value, expiration)
+	}
+}
+
+func (c *LayeredCache) dropL1(key string) {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	c.gens[c.stripe(key)]++
+	c.l1.Delete(key)
+}
+
+// De-duplicates concurrent calls for the same key, the later callers wait for
+// and share the result of the first one.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg    sync.WaitGroup
+	value string
+	err   error
+}
+
+func (g *flightGroup) Do(key string, fn func() (string, error)) (string, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err
+	}
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.value, call.err = fn()
+	call.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	return call.value, call.err
+}
@@ -0,0 +1,265 @@
+package cache
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// in-memory L2 cache with pub/sub shared by the instances created from the same bus
+type memL2 struct {
+	L2Cache
+	mu   sync.Mutex
+	data map[string]string
+	bus  *memBus
+}
+
+type memBus struct {
+	mu       sync.Mutex
+	handlers map[string][]func(string)
+}
+
+func newMemL2(bus *memBus) *memL2 {
+	return &memL2{data: map[string]string{}, bus: bus}
+}
+
+func (m *memL2) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.data[key]
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (m *memL2) Save(key string, value string, exp_time_seconds int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value
+	return nil
+}
+
+func (m *memL2) TrySave(key string, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.data[key]; ok {
+		return false, nil
+	}
+	m.data[key] = value
+	return true, nil
+}
+
+func (m *memL2) Delete(key ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range key {
+		delete(m.data, k)
+	}
+	return nil
+}
+
+func (m *memL2) Publish(channel string, message string) error {
+	m.bus.mu.Lock()
+	handlers := m.bus.handlers[channel]
+	m.bus.mu.Unlock()
+	for _, h := range handlers {
+		h(message)
+	}
+	return nil
+}
+
+func (m *memL2) Subscribe(channel string, handler func(message string)) (io.Closer, error) {
+	m.bus.mu.Lock()
+	defer m.bus.mu.Unlock()
+	if m.bus.handlers == nil {
+		m.bus.handlers = map[string][]func(string){}
+	}
+	m.bus.handlers[channel] = append(m.bus.handlers[channel], handler)
+	return m, nil
+}
+
+func (m *memL2) Close() error {
+	return nil
+}
+
+func TestLayeredCache(t *testing.T) {
+	l2 := newMemL2(&memBus{})
+	c, err := NewLayeredCache(NewCache(time.Minute, time.Minute), l2, "invalidate")
+	assert.Nil(t, err)
+	defer c.Close()
+	c.NegativeExpiration = time.Minute
+
+	var loads int32
+	loader := func(key string) (string, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(10 * time.Millisecond)
+		if key == "missing" {
+			return "", ErrKeyNotFound
+		}
+		return "value of " + key, nil
+	}
+
+	// concurrent misses load once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.Get("a", loader)
+			assert.Nil(t, err)
+			assert.Equal(t, "value of a", v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+	v, err := l2.Get("a")
+	assert.Nil(t, err)
+	assert.Equal(t, "value of a", v)
+
+	// served from L1
+	l2.Delete("a")
+	v, err = c.Get("a", loader)
+	assert.Nil(t, err)
+	assert.Equal(t, "value of a", v)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	// negative cache
+	_, err = c.Get("missing", loader)
+	assert.Equal(t, ErrKeyNotFound, err)
+	_, err = c.Get("missing", loader)
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads))
+
+	// no loader
+	_, err = c.Get("b", nil)
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	// loader errors are not cached
+	failing := func(key string) (string, error) {
+		return "", errors.New("source down")
+	}
+	_, err = c.Get("c", failing)
+	assert.EqualError(t, err, "source down")
+	v, err = c.Get("c", loader)
+	assert.Nil(t, err)
+	assert.Equal(t, "value of c", v)
+}
+
+func TestLayeredCache_Invalidate(t *testing.T) {
+	bus := &memBus{}
+	l2 := newMemL2(bus)
+	c1, err := NewLayeredCache(NewCache(time.Minute, time.Minute), l2, "invalidate")
+	assert.Nil(t, err)
+	defer c1.Close()
+	c2, err := NewLayeredCache(NewCache(time.Minute, time.Minute), l2, "invalidate")
+	assert.Nil(t, err)
+	defer c2.Close()
+
+	assert.Nil(t, c1.Save("k", "v1", 0))
+	v, err := c2.Get("k", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", v)
+
+	// the write of c1 drops the copy in the L1 of c2
+	assert.Nil(t, c1.Save("k", "v2", 0))
+	v, err = c2.Get("k", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+
+	assert.Nil(t, c2.Delete("k"))
+	_, err = c1.Get("k", nil)
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	// the source changed, the next Get loads it again
+	assert.Nil(t, c1.Save("k", "v3", 0))
+	assert.Nil(t, c2.Invalidate("k"))
+	_, err = l2.Get("k")
+	assert.Equal(t, ErrKeyNotFound, err)
+	v, err = c1.Get("k", func(key string) (string, error) {
+		return "v4", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "v4", v)
+}
+
+func TestLayeredCache_InvalidateDuringLoad(t *testing.T) {
+	bus := &memBus{}
+	l2 := newMemL2(bus)
+	c1, err := NewLayeredCache(NewCache(time.Minute, time.Minute), l2, "invalidate")
+	assert.Nil(t, err)
+	defer c1.Close()
+	c2, err := NewLayeredCache(NewCache(time.Minute, time.Minute), l2, "invalidate")
+	assert.Nil(t, err)
+	defer c2.Close()
+
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan string)
+	go func() {
+		v, err := c1.Get("k", func(key string) (string, error) {
+			close(loading)
+			<-release
+			return "v1", nil
+		})
+		assert.Nil(t, err)
+		done <- v
+	}()
+	<-loading
+	// the source changed while c1 was loading the previous value
+	assert.Nil(t, c2.Invalidate("k"))
+	close(release)
+	assert.Equal(t, "v1", <-done)
+	_, found := c1.l1.Get("k")
+	assert.False(t, found)
+	_, err = l2.Get("k")
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	// the next loads are cached again
+	v, err := c1.Get("k", func(key string) (string, error) {
+		return "v2", nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+	_, found = c1.l1.Get("k")
+	assert.True(t, found)
+	v, err = l2.Get("k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+}
+
+func TestLayeredCache_SaveDuringLoad(t *testing.T) {
+	bus := &memBus{}
+	l2 := newMemL2(bus)
+	c1, err := NewLayeredCache(NewCache(time.Minute, time.Minute), l2, "invalidate")
+	assert.Nil(t, err)
+	defer c1.Close()
+	c2, err := NewLayeredCache(NewCache(time.Minute, time.Minute), l2, "invalidate")
+	assert.Nil(t, err)
+	defer c2.Close()
+
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan string)
+	go func() {
+		v, err := c1.Get("k", func(key string) (string, error) {
+			close(loading)
+			<-release
+			return "v1", nil
+		})
+		assert.Nil(t, err)
+		done <- v
+	}()
+	<-loading
+	// the value written meanwhile is not overwritten by the one loaded before it
+	assert.Nil(t, c2.Save("k", "v2", 0))
+	close(release)
+	assert.Equal(t, "v2", <-done)
+	v, err := l2.Get("k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", v)
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"datamesh.com/common/utils/randgen"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLockNotAcquired = errors.New("lock not acquired")
+	ErrLockNotHeld     = errors.New("lock not held")
+)
+
+// delete, extend or check the key only if it still holds our token
+const (
+	releaseScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+	refreshScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+	heldScript    = `if redis.call("get", KEYS[1]) == ARGV[1] then return 1 else return 0 end`
+)
+
+// Distributed locks.
+// With a single cache the lock is a key holding a random token, set if it does not exist.
+// With several independent caches (not replicas of each other) it works as Redlock:
+// the lock is held if it is set on a majority of them within its TTL.
+// see: https://redis.io/topics/distlock
+type Locker struct {
+	// Number of attempts of Acquire, 1 if <= 0.
+	RetryCount int
+	// Delay between the attempts, a random jitter of up to the same amount is added.
+	RetryDelay time.Duration
+
+	caches []L2Cache
+	quorum int
+}
+
+func NewLocker(caches ...L2Cache) *Locker {
+	if len(caches) == 0 {
+		panic("Uninitialized cache.")
+	}
+	return &Locker{RetryDelay: 100 * time.Millisecond, caches: caches, quorum: len(caches)/2 + 1}
+}
+
+// A lock acquired by Locker, owned by its token.
+type Lock struct {
+	Key string
+
+	locker *Locker
+	token  string
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	released bool
+	stop     chan struct{}
+	lost     chan struct{}
+}
+
+// Acquire the lock of key for ttl, returns ErrLockNotAcquired if it is held by someone else after all the attempts.
+// The lock never expires if ttl <= 0, like with TrySave, it is held until released.
+func (l *Locker) Acquire(key string, ttl time.Duration) (*Lock, error) {
+	token := randgen.GenUUID()
+	attempts := l.RetryCount
+	if attempts <= 0 {
+		attempts = 1
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(l.RetryDelay + time.Duration(randgen.GenRandBytes(1)[0])*l.RetryDelay/256)
+		}
+		start := time.Now()
+		var n int
+		n, err = l.each(func(c L2Cache) (bool, error) {
+			return c.TrySave(key, token, ttl)
+		})
+		// the clocks of the servers may drift a little during the ttl
+		drift := ttl/100 + 2*time.Millisecond
+		if n >= l.quorum && (ttl <= 0 || time.Since(start)+drift < ttl) {
+			return &Lock{Key: key, locker: l, token: token, ttl: ttl, lost: make(chan struct{})}, nil
+		}
+		l.each(func(c L2Cache) (bool, error) {
+			return evalOwned(c, releaseScript, key, token)
+		})
+	}
+	if err == nil {
+		err = ErrLockNotAcquired
+	}
+	return nil, err
+}
+
+// Call fn on all the caches, returns the number of them which returned true,
+// and the last error if there was no quorum.
+func (l *Locker) each(fn func(c L2Cache) (bool, error)) (int, error) {
+	if len(l.caches) == 1 {
+		ok, err := fn(l.caches[0])
+		if ok {
+			return 1, nil
+		}
+		return 0, err
+	}
+	oks := make([]bool, len(l.caches))
+	errs := make([]error, len(l.caches))
+	var wg sync.WaitGroup
+	for i, c := range l.caches {
+		wg.Add(1)
+		go func(i int, c L2Cache) {
+			defer wg.Done()
+			oks[i], errs[i] = fn(c)
+		}(i, c)
+	}
+	wg.Wait()
+	n := 0
+	var lastErr error
+	for i := range l.caches {
+		if oks[i] {
+			n++
+		} else if errs[i] != nil {
+			lastErr = errs[i]
+		}
+	}
+	if n >= l.quorum {
+		return n, nil
+	}
+	return n, lastErr
+}
+
+func evalOwned(c L2Cache, script string, key string, token string, args ...interface{}) (bool, error) {
+	ret, err := c.Eval(script, []string{key}, append([]interface{}{token}, args...)...)
+	if err != nil {
+		return false, err
+	}
+	n, _ := ret.(int64)
+	return n == 1, nil
+}
+
+// Random token identifying the owner of the lock.
+func (lk *Lock) Token() string {
+	return lk.token
+}
+
+// Extend the lock to ttl from now, returns ErrLockNotHeld if it expired or was taken by someone else.
+// A lock which never expires is only checked.
+func (lk *Lock) Refresh() error {
+	n, err := lk.locker.each(func(c L2Cache) (bool, error) {
+		if lk.ttl <= 0 {
+			return evalOwned(c, heldScript, lk.Key, lk.token)
+		}
+		return evalOwned(c, refreshScript, lk.Key, lk.token, int64(lk.ttl/time.Millisecond))
+	})
+	if n >= lk.locker.quorum {
+		return nil
+	}
+	if err == nil {
+		err = ErrLockNotHeld
+	}
+	return err
+}
+
+// Refresh the lock every third of its ttl until it is released.
+// If it can not be refreshed in time, the channel returned by Lost is closed and the renewal stops.
+func (lk *Lock) AutoRenew() {
+	lk.mu.Lock()
+	defer lk.mu.Unlock()
+	if lk.stop != nil || lk.released || lk.ttl <= 0 {
+		return
+	}
+	lk.stop = make(chan struct{})
+	go lk.renew(lk.stop)
+}
+
+func (lk *Lock) renew(stop chan struct{}) {
+	ticker := time.NewTicker(lk.ttl / 3)
+	defer ticker.Stop()
+	deadline := time.Now().Add(lk.ttl)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		err := lk.Refresh()
+		if err == nil {
+			deadline = time.Now().Add(lk.ttl)
+			continue
+		}
+		// retry transient errors until the lock would have expired anyway
+		if err == ErrLockNotHeld || time.Now().After(deadline) {
+			close(lk.lost)
+			return
+		}
+	}
+}
+
+// Closed when the lock is lost while being renewed automatically, the work it protects should be stopped.
+func (lk *Lock) Lost() <-chan struct{} {
+	return lk.lost
+}
+
+// Release the lock, returns ErrLockNotHeld if it expired or was taken by someone else in the meantime.
+func (lk *Lock) Release() error {
+	lk.mu.Lock()
+	if lk.stop != nil {
+		close(lk.stop)
+		lk.stop = nil
+	}
+	lk.released = true
+	lk.mu.Unlock()
+
+	n, err := lk.locker.each(func(c L2Cache) (bool, error) {
+		return evalOwned(c, releaseScript, lk.Key, lk.token)
+	})
+	if n >= lk.locker.quorum {
+		return nil
+	}
+	if err == nil {
+		err = ErrLockNotHeld
+	}
+	return err
+}
@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/gopher-lua"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("cache down")
+
+// Redis on miniredis, which lacks EVAL, so the scripts are run here by a Lua interpreter
+// whose redis.call sends the commands to miniredis.
+type scriptedRedis struct {
+	*Redis
+	server *miniredis.Miniredis
+	down   bool
+}
+
+func newScriptedRedis(t *testing.T) *scriptedRedis {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &scriptedRedis{Redis: NewRedis(s.Addr(), "", 0), server: s}
+}
+
+func (r *scriptedRedis) Close() error {
+	r.Redis.Close()
+	r.server.Close()
+	return nil
+}
+
+func (r *scriptedRedis) TrySave(key string, value string, ttl time.Duration) (bool, error) {
+	if r.down {
+		return false, errDown
+	}
+	return r.Redis.TrySave(key, value, ttl)
+}
+
+func (r *scriptedRedis) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	if r.down {
+		return nil, errDown
+	}
+	L := lua.NewState()
+	defer L.Close()
+	table := func(values []string) *lua.LTable {
+		t := L.NewTable()
+		for i, v := range values {
+			L.RawSetInt(t, i+1, lua.LString(v))
+		}
+		return t
+	}
+	argv := make([]string, len(args))
+	for i, a := range args {
+		argv[i] = fmt.Sprint(a)
+	}
+	L.SetGlobal("KEYS", table(keys))
+	L.SetGlobal("ARGV", table(argv))
+	call := L.NewFunction(func(L *lua.LState) int {
+		cmdArgs := make([]interface{}, L.GetTop())
+		for i := range cmdArgs {
+			cmdArgs[i] = L.Get(i + 1).String()
+		}
+		cmd := redis.NewCmd(cmdArgs...)
+		r.client.Process(cmd)
+		ret, err := cmd.Result()
+		switch v := ret.(type) {
+		case int64:
+			L.Push(lua.LNumber(v))
+		case string:
+			L.Push(lua.LString(v))
+		default:
+			if err != nil && err != redis.Nil {
+				L.RaiseError("%v", err)
+			}
+			// a nil bulk reply is false in Lua
+			L.Push(lua.LFalse)
+		}
+		return 1
+	})
+	redisTable := L.NewTable()
+	L.SetField(redisTable, "call", call)
+	L.SetGlobal("redis", redisTable)
+	if err := L.DoString(script); err != nil {
+		return nil, err
+	}
+	switch v := L.Get(-1).(type) {
+	case lua.LNumber:
+		return int64(v), nil
+	case lua.LString:
+		return string(v), nil
+	}
+	return nil, nil
+}
+
+func TestLocker(t *testing.T) {
+	c := newScriptedRedis(t)
+	defer c.Close()
+	locker := NewLocker(c)
+
+	lock, err := locker.Acquire("job", time.Second)
+	assert.Nil(t, err)
+	_, err = locker.Acquire("job", time.Second)
+	assert.Equal(t, ErrLockNotAcquired, err)
+	assert.Nil(t, lock.Refresh())
+	assert.Nil(t, lock.Release())
+	assert.Equal(t, ErrLockNotHeld, lock.Release())
+
+	// retries until released
+	lock, err = locker.Acquire("job", time.Second)
+	assert.Nil(t, err)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lock.Release()
+	}()
+	locker.RetryCount = 10
+	locker.RetryDelay = 20 * time.Millisecond
+	lock2, err := locker.Acquire("job", time.Second)
+	assert.Nil(t, err)
+	assert.NotEqual(t, lock.Token(), lock2.Token())
+
+	// the lock is lost when taken by someone else
+	lock2.AutoRenew()
+	c.Delete("job")
+	c.Save("job", "someone else", 0)
+	select {
+	case <-lock2.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lock not lost")
+	}
+	assert.Equal(t, ErrLockNotHeld, lock2.Release())
+}
+
+func TestLocker_Expiry(t *testing.T) {
+	c := newScriptedRedis(t)
+	defer c.Close()
+	locker := NewLocker(c)
+
+	lock, err := locker.Acquire("job", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, c.server.TTL("job"))
+	c.server.FastForward(600 * time.Millisecond)
+	assert.Nil(t, lock.Refresh())
+	assert.Equal(t, time.Second, c.server.TTL("job"))
+
+	// expired, someone else may take it
+	c.server.FastForward(time.Second)
+	assert.Equal(t, ErrLockNotHeld, lock.Refresh())
+	lock2, err := locker.Acquire("job", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrLockNotHeld, lock.Release())
+	v, err := c.Get("job")
+	assert.Nil(t, err)
+	assert.Equal(t, lock2.Token(), v)
+	assert.Nil(t, lock2.Release())
+
+	// never expires
+	lock, err = locker.Acquire("job", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), c.server.TTL("job"))
+	assert.Nil(t, lock.Refresh())
+	assert.Equal(t, time.Duration(0), c.server.TTL("job"))
+	_, err = locker.Acquire("job", 0)
+	assert.Equal(t, ErrLockNotAcquired, err)
+	assert.Nil(t, lock.Release())
+	assert.False(t, c.server.Exists("job"))
+}
+
+func TestLocker_Redlock(t *testing.T) {
+	caches := []*scriptedRedis{newScriptedRedis(t), newScriptedRedis(t), newScriptedRedis(t)}
+	for _, c := range caches {
+		defer c.Close()
+	}
+	locker := NewLocker(caches[0], caches[1], caches[2])
+
+	// a minority down does not matter
+	caches[2].down = true
+	lock, err := locker.Acquire("job", time.Second)
+	assert.Nil(t, err)
+	assert.Nil(t, lock.Refresh())
+	assert.Nil(t, lock.Release())
+
+	// no majority
+	caches[1].down = true
+	_, err = locker.Acquire("job", time.Second)
+	assert.Equal(t, errDown, err)
+	_, err = caches[0].Get("job")
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	// held on one instance only
+	caches[1].down = false
+	caches[2].down = false
+	caches[1].Save("job", "someone else", 0)
+	caches[2].Save("job", "someone else", 0)
+	_, err = locker.Acquire("job", time.Second)
+	assert.Equal(t, ErrLockNotAcquired, err)
+}
+
+func TestRedis_TrySave(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	client := NewRedis(s.Addr(), "", 0)
+	defer client.Close()
+
+	ok, err := client.TrySave("lock", "a", time.Second)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = client.TrySave("lock", "b", time.Second)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	v, err := client.Get("lock")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", v)
+}
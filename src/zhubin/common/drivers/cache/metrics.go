@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// upper bounds of the latency histogram buckets, in seconds
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// Metrics of the caches instrumented with NewInstrumentedCache and NewInstrumentedL1Cache.
+var L2_CACHE_METRICS = NewMetrics()
+
+// Counters and latency histograms per cache and operation, exported in the Prometheus text format.
+// It is an http.Handler, e.g. with gin:
+//
+//	router.GET("/metrics", gin.WrapH(cache.L2_CACHE_METRICS))
+type Metrics struct {
+	// Operations slower than this are logged, 0 disables the slow log.
+	SlowThreshold time.Duration
+	// Called after every operation if set, e.g. to add a span to a trace.
+	OnOperation func(cache string, op string, key string, elapsed time.Duration, err error)
+
+	mu  sync.Mutex
+	ops map[opName]*opStats
+}
+
+type opName struct {
+	cache string
+	op    string
+}
+
+type opStats struct {
+	calls   uint64
+	errors  uint64
+	hits    uint64
+	misses  uint64
+	buckets []uint64
+	sum     float64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{ops: make(map[opName]*opStats)}
+}
+
+// Record an operation started at start.
+// ErrKeyNotFound counts as a miss rather than an error, hits and misses are only counted for the lookups.
+func (m *Metrics) observe(cache string, op string, key string, start time.Time, lookup bool, err error) {
+	elapsed := time.Since(start)
+	m.mu.Lock()
+	s := m.stats(cache, op)
+	s.calls++
+	switch {
+	case err == ErrKeyNotFound:
+		s.misses++
+	case err != nil:
+		s.errors++
+	case lookup:
+		s.hits++
+	}
+	seconds := elapsed.Seconds()
+	s.sum += seconds
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			s.buckets[i]++
+		}
+	}
+	m.mu.Unlock()
+
+	if m.SlowThreshold > 0 && elapsed >= m.SlowThreshold {
+		logrus.WithFields(logrus.Fields{
+			"cache":   cache,
+			"op":      op,
+			"key":     key,
+			"elapsed": elapsed.String(),
+		}).Warn("slow cache operation")
+	}
+	if m.OnOperation != nil {
+		m.OnOperation(cache, op, key, elapsed, err)
+	}
+}
+
+// Count hits and misses of a batch lookup.
+func (m *Metrics) lookups(cache string, op string, hits int, misses int) {
+	m.mu.Lock()
+	s := m.stats(cache, op)
+	s.hits += uint64(hits)
+	s.misses += uint64(misses)
+	m.mu.Unlock()
+}
+
+// must hold mu
+func (m *Metrics) stats(cache string, op string) *opStats {
+	name := opName{cache: cache, op: op}
+	s, ok := m.ops[name]
+	if !ok {
+		s = &opStats{buckets: make([]uint64, len(latencyBuckets))}
+		m.ops[name] = s
+	}
+	return s
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
+
+// Write the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	names := make([]opName, 0, len(m.ops))
+	stats := make(map[opName]opStats, len(m.ops))
+	for name, s := range m.ops {
+		names = append(names, name)
+		copied := *s
+		copied.buckets = append([]uint64(nil), s.buckets...)
+		stats[name] = copied
+	}
+	m.mu.Unlock()
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].cache != names[j].cache {
+			return names[i].cache < names[j].cache
+		}
+		return names[i].op < names[j].op
+	})
+
+	cw := &countWriter{w: w}
+	counters := []struct {
+		name  string
+		help  string
+		value func(s opStats) uint64
+	}{
+		{"cache_operations_total", "Number of cache operations.", func(s opStats) uint64 { return s.calls }},
+		{"cache_errors_total", "Number of failed cache operations.", func(s opStats) uint64 { return s.errors }},
+		{"cache_hits_total", "Number of keys found by cache lookups.", func(s opStats) uint64 { return s.hits }},
+		{"cache_misses_total", "Number of keys not found by cache lookups.", func(s opStats) uint64 { return s.misses }},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		for _, name := range names {
+			fmt.Fprintf(cw, "%s{cache=%q,op=%q} %d\n", c.name, name.cache, name.op, c.value(stats[name]))
+		}
+	}
+
+	const hist = "cache_operation_duration_seconds"
+	fmt.Fprintf(cw, "# HELP %s Latency of cache operations.\n# TYPE %s histogram\n", hist, hist)
+	for _, name := range names {
+		s := stats[name]
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(cw, "%s_bucket{cache=%q,op=%q,le=%q} %d\n", hist, name.cache, name.op, strconv.FormatFloat(le, 'g', -1, 64), s.buckets[i])
+		}
+		fmt.Fprintf(cw, "%s_bucket{cache=%q,op=%q,le=\"+Inf\"} %d\n", hist, name.cache, name.op, s.calls)
+		fmt.Fprintf(cw, "%s_sum{cache=%q,op=%q} %g\n", hist, name.cache, name.op, s.sum)
+		fmt.Fprintf(cw, "%s_count{cache=%q,op=%q} %d\n", hist, name.cache, name.op, s.calls)
+	}
+	return cw.n, cw.err
+}
+
+// keeps the number of bytes written and the first error
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"bytes"
+	"github.com/alicebob/miniredis"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstrumentedCache(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	metrics := NewMetrics()
+	var traced []string
+	metrics.OnOperation = func(cache string, op string, key string, elapsed time.Duration, err error) {
+		traced = append(traced, cache+" "+op+" "+key)
+	}
+	client := NewInstrumentedCache("l2", NewRedis(s.Addr(), "", 0), metrics)
+	defer client.Close()
+	_, ok := client.(PubSub)
+	assert.True(t, ok)
+
+	assert.Nil(t, client.Save("a", "1", 0))
+	client.Get("a")
+	client.Get("missing")
+	client.MGet("a", "b", "c")
+	client.HSet("a", "f", "v") // WRONGTYPE
+
+	l1 := NewInstrumentedL1Cache("l1", NewCache(time.Minute, time.Minute), metrics)
+	l1.Set("k", 1, 0)
+	l1.Get("k")
+	assert.Equal(t, []string{"l2 save a", "l2 get a", "l2 get missing", "l2 mget a", "l2 hset a", "l1 set k", "l1 get k"}, traced)
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		`cache_operations_total{cache="l2",op="get"} 2`,
+		`cache_hits_total{cache="l2",op="get"} 1`,
+		`cache_misses_total{cache="l2",op="get"} 1`,
+		`cache_hits_total{cache="l2",op="mget"} 1`,
+		`cache_misses_total{cache="l2",op="mget"} 2`,
+		`cache_errors_total{cache="l2",op="hset"} 1`,
+		`cache_hits_total{cache="l1",op="get"} 1`,
+		`cache_operation_duration_seconds_bucket{cache="l2",op="save",le="+Inf"} 1`,
+		`cache_operation_duration_seconds_count{cache="l2",op="get"} 2`,
+	} {
+		assert.True(t, strings.Contains(body, line+"\n"), line)
+	}
+}
+
+func TestMetrics_SlowLog(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.SlowThreshold = time.Millisecond
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	defer logrus.SetOutput(os.Stderr)
+
+	metrics.observe("l2", "get", "fast", time.Now(), true, nil)
+	metrics.observe("l2", "get", "slow", time.Now().Add(-time.Second), true, nil)
+	assert.False(t, strings.Contains(buf.String(), "fast"))
+	assert.True(t, strings.Contains(buf.String(), "slow cache operation"))
+	assert.True(t, strings.Contains(buf.String(), "key=slow"))
+}
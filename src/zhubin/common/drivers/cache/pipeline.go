@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis"
+	radixCluster "github.com/mediocregopher/radix.v2/cluster"
+	radix "github.com/mediocregopher/radix.v2/redis"
+	"sync"
+)
+
+var ErrCrossSlot = errors.New("keys of the transaction are not in the same slot")
+
+// Commands queued and sent together, in a single round trip per server:
+//
+//	p := client.Pipeline()
+//	p.Cmd("HSET", "user:1", "name", "alice")
+//	p.Cmd("EXPIRE", "user:1", 3600)
+//	replies, err := p.Exec()
+//
+// The key of a command must be its first argument after the name of the command.
+type Pipeline interface {
+	// Queue a command.
+	Cmd(args ...interface{})
+
+	// Send the queued commands and return their replies in order, of the same types as the ones of Eval.
+	// The reply of a failed command is its error, and the error of the first failed command is returned as well.
+	// The pipeline is empty again afterwards.
+	Exec() ([]interface{}, error)
+}
+
+type redisPipeline struct {
+	pipe redis.Pipeliner
+	cmds []*redis.Cmd
+}
+
+func (p *redisPipeline) Cmd(args ...interface{}) {
+	cmd := redis.NewCmd(args...)
+	p.pipe.Process(cmd)
+	p.cmds = append(p.cmds, cmd)
+}
+
+func (p *redisPipeline) Exec() ([]interface{}, error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	// the error is the one of the first failed command, which may be a redis.Nil
+	p.pipe.Exec()
+	replies := make([]interface{}, len(cmds))
+	for i, cmd := range cmds {
+		val, cmdErr := cmd.Result()
+		switch {
+		case cmdErr == redis.Nil:
+		case cmdErr != nil:
+			replies[i] = cmdErr
+		default:
+			replies[i] = val
+		}
+	}
+	return replies, firstError(replies)
+}
+
+type clusterCmd struct {
+	name string
+	args []interface{}
+}
+
+func (c clusterCmd) key() string {
+	if len(c.args) == 0 {
+		return ""
+	}
+	return fmt.Sprint(c.args[0])
+}
+
+// The commands are grouped by the node serving their key, and the groups are sent to the nodes in parallel.
+// NOTE a command on a slot being migrated fails with a MOVED or ASK error, it is not redirected.
+type clusterPipeline struct {
+	cluster *radixCluster.Cluster
+	tx      bool
+	cmds    []clusterCmd
+}
+
+func (p *clusterPipeline) Cmd(args ...interface{}) {
+	if len(args) == 0 {
+		return
+	}
+	p.cmds = append(p.cmds, clusterCmd{name: fmt.Sprint(args[0]), args: args[1:]})
+}
+
+func (p *clusterPipeline) Exec() ([]interface{}, error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	if p.tx {
+		return p.execTx(cmds)
+	}
+
+	// indexes of the commands by node
+	nodes := make(map[string][]int)
+	for i, cmd := range cmds {
+		addr := p.cluster.GetAddrForKey(cmd.key())
+		nodes[addr] = append(nodes[addr], i)
+	}
+	replies := make([]interface{}, len(cmds))
+	var wg sync.WaitGroup
+	for _, indexes := range nodes {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			conn, err := p.cluster.GetForKey(cmds[indexes[0]].key())
+			if err != nil {
+				for _, i := range indexes {
+					replies[i] = err
+				}
+				return
+			}
+			defer p.cluster.Put(conn)
+			for _, i := range indexes {
+				conn.PipeAppend(cmds[i].name, cmds[i].args...)
+			}
+			for _, i := range indexes {
+				replies[i] = replyValue(conn.PipeResp())
+			}
+		}(indexes)
+	}
+	wg.Wait()
+	return replies, firstError(replies)
+}
+
+// A transaction runs on a single node, so all the keys must be in the same slot, e.g. with a hash tag: {user:1}:name
+func (p *clusterPipeline) execTx(cmds []clusterCmd) ([]interface{}, error) {
+	key := cmds[0].key()
+	for _, cmd := range cmds[1:] {
+		if radixCluster.Slot(cmd.key()) != radixCluster.Slot(key) {
+			return nil, ErrCrossSlot
+		}
+	}
+	conn, err := p.cluster.GetForKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer p.cluster.Put(conn)
+	conn.PipeAppend("MULTI")
+	for _, cmd := range cmds {
+		conn.PipeAppend(cmd.name, cmd.args...)
+	}
+	conn.PipeAppend("EXEC")
+	var queueErr error
+	for i := 0; i <= len(cmds); i++ {
+		if r := conn.PipeResp(); r.Err != nil && queueErr == nil {
+			queueErr = r.Err
+		}
+	}
+	resp := conn.PipeResp()
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	if queueErr != nil {
+		return nil, queueErr
+	}
+	elems, err := resp.Array()
+	if err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, len(elems))
+	for i, e := range elems {
+		replies[i] = replyValue(e)
+	}
+	return replies, firstError(replies)
+}
+
+// the value of resp, or its error
+func replyValue(resp *radix.Resp) interface{} {
+	v, err := respValue(resp)
+	if err != nil {
+		return err
+	}
+	return v
+}
+
+func firstError(replies []interface{}) error {
+	for _, r := range replies {
+		if err, ok := r.(error); ok {
+			return err
+		}
+	}
+	return nil
+}
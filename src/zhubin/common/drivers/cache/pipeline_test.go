@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRedis_Batch(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	client := NewRedis(s.Addr(), "", 0)
+	defer client.Close()
+
+	assert.Nil(t, client.MSave(map[string]string{"a": "1", "b": "2", "c": ""}, 60))
+	assert.True(t, s.TTL("a") > 0)
+	vals, err := client.MGet("a", "missing", "b", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2", "c": ""}, vals)
+
+	n, err := client.MDelete("a", "b", "missing")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+	vals, err = client.MGet("a", "b", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"c": ""}, vals)
+}
+
+func TestRedis_Pipeline(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	client := NewRedis(s.Addr(), "", 0)
+	defer client.Close()
+
+	p := client.Pipeline()
+	p.Cmd("SET", "counter", "1")
+	p.Cmd("INCR", "counter")
+	p.Cmd("GET", "missing")
+	p.Cmd("HSET", "counter", "field", "v")
+	p.Cmd("GET", "counter")
+	replies, err := p.Exec()
+	assert.NotNil(t, err)
+	assert.Len(t, replies, 5)
+	assert.Equal(t, "OK", replies[0])
+	assert.Equal(t, int64(2), replies[1])
+	assert.Nil(t, replies[2])
+	assert.Equal(t, err, replies[3])
+	assert.Equal(t, "2", replies[4])
+
+	replies, err = p.Exec()
+	assert.Nil(t, err)
+	assert.Nil(t, replies)
+
+	tx := client.TxPipeline()
+	tx.Cmd("INCR", "counter")
+	tx.Cmd("LPUSH", "list", "x")
+	replies, err = tx.Exec()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(3), int64(1)}, replies)
+}
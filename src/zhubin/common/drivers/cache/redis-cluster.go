@@ -1,19 +1,36 @@
 package cache
 
 import (
+	"errors"
 	radixCluster "github.com/mediocregopher/radix.v2/cluster"
+	"github.com/mediocregopher/radix.v2/pubsub"
 	radix "github.com/mediocregopher/radix.v2/redis"
+	"github.com/sirupsen/logrus"
+	"io"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // L2 cache implementation.
 // use Radix client because it handles cluster structure transparently, though it is not as type-safe as go-redis client.
 type RedisCluster struct {
+	// Codec of SaveObject and GetObject, JSONCodec if nil.
+	Codec   Codec
 	cluster *radixCluster.Cluster
 }
 
 func NewRedisCluster(address string, password string) *RedisCluster {
+	cluster, err := DialRedisCluster(address, password)
+	if err != nil {
+		panic(err)
+	}
+	return cluster
+}
+
+// Like NewRedisCluster, but returns an error if the cluster can not be reached.
+func DialRedisCluster(address string, password string) (*RedisCluster, error) {
 	env_password = password
 	opts := radixCluster.Opts{
 		Addr:     address,
@@ -23,9 +40,9 @@ func NewRedisCluster(address string, password string) *RedisCluster {
 	}
 	cs, err := radixCluster.NewWithOpts(opts)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return &RedisCluster{cluster: cs}
+	return &RedisCluster{cluster: cs}, nil
 }
 
 var env_password string
@@ -47,8 +64,17 @@ func dialFunc(network, address string) (*radix.Client, error) {
 }
 
 func (s *RedisCluster) Keys(pattern string) ([]string, error) {
-	// ref: http://redis.io/commands/KEYS
-	return s.cluster.Cmd("KEYS", pattern).List()
+	// KEYS would only run on the node the pattern hashes to
+	return scanKeys(s.Scan(pattern, 0))
+}
+
+func (s *RedisCluster) Scan(pattern string, count int) KeyIterator {
+	return newClusterKeyIterator(s.cluster, pattern, count)
+}
+
+// The keys are deleted with MDelete, a DEL of several keys fails unless they are in the same slot.
+func (s *RedisCluster) DeleteByPattern(pattern string) (int64, error) {
+	return deleteKeys(s.Scan(pattern, deleteBatchSize), s.MDelete)
 }
 
 // Get fetches value by given key from redis.
@@ -67,20 +93,13 @@ func (s *RedisCluster) Get(key string) (value string, err error) {
 	return v, nil
 }
 
-// Get fetches value by given key from redis.
-func (s *RedisCluster) GetObject(key string) (value interface{}, err error) {
-	resp := s.cluster.Cmd("GET", key)
-	if resp.Err != nil {
-		return "", resp.Err
-	}
-	// NOTE: bugfix by Jianjun Xie
-	// we now report errNil when no data associated with the key instead of nil error with empty string,
-	// since we do allow to store empty string as value
-	v, err := resp.Str()
-	if err == radix.ErrRespNil {
-		return "", ErrKeyNotFound
+// GetObject decodes the value saved by SaveObject into out.
+func (s *RedisCluster) GetObject(key string, out interface{}) error {
+	val, err := s.Get(key)
+	if err != nil {
+		return err
 	}
-	return v, nil
+	return decodeObject(s.Codec, val, out)
 }
 
 // Stores the key and corresponding value in Redis.
@@ -93,6 +112,10 @@ func (s *RedisCluster) Save(key string, value string, exp_time_seconds int) erro
 
 // Stores the key and corresponding value in Redis.
 func (s *RedisCluster) SaveObject(key string, value interface{}, exp_time_seconds int) error {
+	value, err := encodeObject(s.Codec, value)
+	if err != nil {
+		return err
+	}
 	if exp_time_seconds <= 0 {
 		return s.cluster.Cmd("SET", key, value).Err
 	}
@@ -106,11 +129,66 @@ func (s *RedisCluster) SaveIfNotExists(key string, value string, exp_time_second
 }
 
 func (s *RedisCluster) SaveObjectIfNotExists(key string, value interface{}, exp_time_seconds int) error {
+	value, err := encodeObject(s.Codec, value)
+	if err != nil {
+		return err
+	}
 	// use new command since 2.6.12
 	// see: https://redis.io/commands/set
 	return s.cluster.Cmd("set", key, value, "ex", exp_time_seconds, "nx").Err
 }
 
+func (s *RedisCluster) TrySave(key string, value string, ttl time.Duration) (bool, error) {
+	var resp *radix.Resp
+	if ttl > 0 {
+		resp = s.cluster.Cmd("SET", key, value, "PX", int64(ttl/time.Millisecond), "NX")
+	} else {
+		resp = s.cluster.Cmd("SET", key, value, "NX")
+	}
+	if resp.Err != nil {
+		return false, resp.Err
+	}
+	return !resp.IsType(radix.Nil), nil
+}
+
+// The connection is picked with the first key, radix can not route EVAL by itself.
+func (s *RedisCluster) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("eval on cluster needs at least one key")
+	}
+	conn, err := s.cluster.GetForKey(keys[0])
+	if err != nil {
+		return nil, err
+	}
+	defer s.cluster.Put(conn)
+	return respValue(conn.Cmd("EVAL", script, len(keys), keys, args))
+}
+
+func respValue(resp *radix.Resp) (interface{}, error) {
+	switch {
+	case resp.Err != nil:
+		return nil, resp.Err
+	case resp.IsType(radix.Nil):
+		return nil, nil
+	case resp.IsType(radix.Int):
+		return resp.Int64()
+	case resp.IsType(radix.Array):
+		elems, err := resp.Array()
+		if err != nil {
+			return nil, err
+		}
+		ret := make([]interface{}, len(elems))
+		for i, e := range elems {
+			if ret[i], err = respValue(e); err != nil {
+				return nil, err
+			}
+		}
+		return ret, nil
+	default:
+		return resp.Str()
+	}
+}
+
 // check if key exists
 func (s *RedisCluster) Exists(key string) (bool, error) {
 	resp := s.cluster.Cmd("EXISTS", key)
@@ -136,7 +214,260 @@ func (s *RedisCluster) Delete(key ...string) error {
 	return s.cluster.Cmd("DEL", key).Err
 }
 
+// The keys are sent in parallel to the nodes serving them, see Pipeline.
+func (s *RedisCluster) MGet(key ...string) (map[string]string, error) {
+	p := s.Pipeline()
+	for _, k := range key {
+		p.Cmd("GET", k)
+	}
+	replies, err := p.Exec()
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]string)
+	for i, r := range replies {
+		if str, ok := r.(string); ok {
+			ret[key[i]] = str
+		}
+	}
+	return ret, nil
+}
+
+func (s *RedisCluster) MSave(values map[string]string, exp_time_seconds int) error {
+	p := s.Pipeline()
+	for k, v := range values {
+		if exp_time_seconds <= 0 {
+			p.Cmd("SET", k, v)
+		} else {
+			p.Cmd("SETEX", k, exp_time_seconds, v)
+		}
+	}
+	_, err := p.Exec()
+	return err
+}
+
+func (s *RedisCluster) MDelete(key ...string) (int64, error) {
+	p := s.Pipeline()
+	for _, k := range key {
+		p.Cmd("DEL", k)
+	}
+	replies, err := p.Exec()
+	var deleted int64
+	for _, r := range replies {
+		if n, ok := r.(int64); ok {
+			deleted += n
+		}
+	}
+	return deleted, err
+}
+
+func (s *RedisCluster) Pipeline() Pipeline {
+	return &clusterPipeline{cluster: s.cluster}
+}
+
+func (s *RedisCluster) TxPipeline() Pipeline {
+	return &clusterPipeline{cluster: s.cluster, tx: true}
+}
+
+func (s *RedisCluster) HGet(key string, field string) (string, error) {
+	return clusterStr(s.cluster.Cmd("HGET", key, field))
+}
+
+func (s *RedisCluster) HSet(key string, field string, value string) error {
+	return s.cluster.Cmd("HSET", key, field, value).Err
+}
+
+func (s *RedisCluster) HGetAll(key string) (map[string]string, error) {
+	return s.cluster.Cmd("HGETALL", key).Map()
+}
+
+func (s *RedisCluster) HIncrBy(key string, field string, incValue int64) (int64, error) {
+	return s.cluster.Cmd("HINCRBY", key, field, incValue).Int64()
+}
+
+func (s *RedisCluster) HDel(key string, field ...string) error {
+	return s.cluster.Cmd("HDEL", key, field).Err
+}
+
+func (s *RedisCluster) LPush(key string, value ...string) (int64, error) {
+	return s.cluster.Cmd("LPUSH", key, value).Int64()
+}
+
+func (s *RedisCluster) RPop(key string) (string, error) {
+	return clusterStr(s.cluster.Cmd("RPOP", key))
+}
+
+func (s *RedisCluster) LRange(key string, start int64, stop int64) ([]string, error) {
+	return s.cluster.Cmd("LRANGE", key, start, stop).List()
+}
+
+func (s *RedisCluster) LLen(key string) (int64, error) {
+	return s.cluster.Cmd("LLEN", key).Int64()
+}
+
+func (s *RedisCluster) SAdd(key string, member ...string) (int64, error) {
+	return s.cluster.Cmd("SADD", key, member).Int64()
+}
+
+func (s *RedisCluster) SRem(key string, member ...string) error {
+	return s.cluster.Cmd("SREM", key, member).Err
+}
+
+func (s *RedisCluster) SMembers(key string) ([]string, error) {
+	return s.cluster.Cmd("SMEMBERS", key).List()
+}
+
+func (s *RedisCluster) SIsMember(key string, member string) (bool, error) {
+	v, err := s.cluster.Cmd("SISMEMBER", key, member).Int()
+	return v != 0, err
+}
+
+func (s *RedisCluster) ZAdd(key string, member ...Z) (int64, error) {
+	args := make([]interface{}, 0, 2*len(member))
+	for _, m := range member {
+		args = append(args, m.Score, m.Member)
+	}
+	return s.cluster.Cmd("ZADD", key, args).Int64()
+}
+
+func (s *RedisCluster) ZIncrBy(key string, member string, incValue float64) (float64, error) {
+	return s.cluster.Cmd("ZINCRBY", key, incValue, member).Float64()
+}
+
+func (s *RedisCluster) ZRangeByScore(key string, min string, max string, offset int64, count int64) ([]Z, error) {
+	if count <= 0 {
+		count = -1
+	}
+	l, err := s.cluster.Cmd("ZRANGEBYSCORE", key, min, max, "WITHSCORES", "LIMIT", offset, count).List()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Z, len(l)/2)
+	for i := range ret {
+		score, err := strconv.ParseFloat(l[2*i+1], 64)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = Z{Score: score, Member: l[2*i]}
+	}
+	return ret, nil
+}
+
+func (s *RedisCluster) ZRevRank(key string, member string) (int64, error) {
+	resp := s.cluster.Cmd("ZREVRANK", key, member)
+	if resp.Err == nil && resp.IsType(radix.Nil) {
+		return 0, ErrKeyNotFound
+	}
+	return resp.Int64()
+}
+
+func (s *RedisCluster) ZRem(key string, member ...string) error {
+	return s.cluster.Cmd("ZREM", key, member).Err
+}
+
+// Reports ErrKeyNotFound for nil replies.
+func clusterStr(resp *radix.Resp) (string, error) {
+	if resp.Err != nil {
+		return "", resp.Err
+	}
+	v, err := resp.Str()
+	if err == radix.ErrRespNil {
+		return "", ErrKeyNotFound
+	}
+	return v, err
+}
+
 func (s *RedisCluster) Close() error {
 	s.cluster.Close()
 	return nil
-}
\ No newline at end of file
+}
+
+// Messages are broadcast to the whole cluster, whichever node they are published to.
+func (s *RedisCluster) Publish(channel string, message string) error {
+	return s.cluster.Cmd("PUBLISH", channel, message).Err
+}
+
+// Subscribe on the node serving channel as a key, on a connection of its own since it can not be shared.
+// The connection is dialed again if it is lost.
+func (s *RedisCluster) Subscribe(channel string, handler func(message string)) (io.Closer, error) {
+	sub := &clusterSub{cluster: s.cluster, channel: channel}
+	if err := sub.dial(); err != nil {
+		return nil, err
+	}
+	go sub.run(handler)
+	return sub, nil
+}
+
+type clusterSub struct {
+	cluster *radixCluster.Cluster
+	channel string
+
+	mu     sync.Mutex
+	client *pubsub.SubClient
+	closed bool
+}
+
+func (c *clusterSub) dial() error {
+	conn, err := dialFunc("tcp", c.cluster.GetAddrForKey(c.channel))
+	if err != nil {
+		return err
+	}
+	client := pubsub.NewSubClient(conn)
+	if r := client.Subscribe(c.channel); r.Err != nil {
+		conn.Close()
+		return r.Err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		conn.Close()
+		return errSubClosed
+	}
+	c.client = client
+	return nil
+}
+
+var errSubClosed = errors.New("subscription closed")
+
+func (c *clusterSub) run(handler func(message string)) {
+	for {
+		r := c.current().Receive()
+		if r.Type == pubsub.Message {
+			handler(r.Message)
+			continue
+		}
+		if r.Err == nil || r.Timeout() {
+			continue
+		}
+		if c.isClosed() {
+			return
+		}
+		logrus.Warnf("redis cluster: subscription to %s lost: %v", c.channel, r.Err)
+		for c.dial() != nil {
+			if c.isClosed() {
+				return
+			}
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// the client is replaced by dial when the connection is lost
+func (c *clusterSub) current() *pubsub.SubClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client
+}
+
+func (c *clusterSub) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func (c *clusterSub) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return c.client.Client.Close()
+}
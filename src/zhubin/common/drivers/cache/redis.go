@@ -2,16 +2,30 @@ package cache
 
 import (
 	"github.com/go-redis/redis"
+	"io"
 	"time"
 )
 
 // L2 cache implementation.
 type Redis struct {
+	// Codec of SaveObject and GetObject, JSONCodec if nil.
+	Codec  Codec
 	client *redis.Client
+	// read only commands go to replica if set
+	replica *redis.Client
 }
 
 // create a redis client.
 func NewRedis(address string, password string, dbnum int) *Redis {
+	rs, err := DialRedis(address, password, dbnum)
+	if err != nil {
+		panic("Failed to connect redis.")
+	}
+	return rs
+}
+
+// Like NewRedis, but returns an error if redis can not be reached.
+func DialRedis(address string, password string, dbnum int) (*Redis, error) {
 	client := redis.NewClient(&redis.Options{
 		Addr:     address,
 		Password: password,
@@ -19,21 +33,39 @@ func NewRedis(address string, password string, dbnum int) *Redis {
 	})
 	_, err := client.Ping().Result()
 	if err != nil {
-		panic("Failed to connect redis.")
+		client.Close()
+		return nil, err
 	}
 	rs := &Redis{
 		client: client,
 	}
-	return rs
+	return rs, nil
+}
+
+func (s *Redis) reader() *redis.Client {
+	if s.replica != nil {
+		return s.replica
+	}
+	return s.client
 }
 
 func (s *Redis) Keys(pattern string) ([]string, error) {
-	return s.client.Keys(pattern).Result()
+	return scanKeys(s.Scan(pattern, 0))
+}
+
+func (s *Redis) Scan(pattern string, count int) KeyIterator {
+	return redisKeyIterator{s.client.Scan(0, pattern, int64(count)).Iterator()}
+}
+
+func (s *Redis) DeleteByPattern(pattern string) (int64, error) {
+	return deleteKeys(s.Scan(pattern, deleteBatchSize), func(keys ...string) (int64, error) {
+		return s.client.Del(keys...).Result()
+	})
 }
 
 // Get fetches value by given key from redis.
 func (s *Redis) Get(key string) (value string, err error) {
-	val, err := s.client.Get(key).Result()
+	val, err := s.reader().Get(key).Result()
 	// NOTE: bugfix by Jianjun Xie
 	// we now report errNil when no data associated with the key instead of nil error with empty string,
 	// since we do allow to store empty string as value
@@ -43,11 +75,23 @@ func (s *Redis) Get(key string) (value string, err error) {
 	return val, err
 }
 
+func (s *Redis) GetObject(key string, out interface{}) error {
+	val, err := s.Get(key)
+	if err != nil {
+		return err
+	}
+	return decodeObject(s.Codec, val, out)
+}
+
 func (s *Redis) Save(key string, value string, exp_time_seconds int) error {
 	return s.client.Set(key, value, time.Second*time.Duration(exp_time_seconds)).Err()
 }
 
 func (s *Redis) SaveObject(key string, value interface{}, exp_time_seconds int) error {
+	value, err := encodeObject(s.Codec, value)
+	if err != nil {
+		return err
+	}
 	return s.client.Set(key, value, time.Second*time.Duration(exp_time_seconds)).Err()
 }
 
@@ -56,11 +100,30 @@ func (s *Redis) SaveIfNotExists(key string, value string, exp_time_seconds int)
 }
 
 func (s *Redis) SaveObjectIfNotExists(key string, value interface{}, exp_time_seconds int) error {
+	value, err := encodeObject(s.Codec, value)
+	if err != nil {
+		return err
+	}
 	return s.client.SetNX(key, value, time.Second*time.Duration(exp_time_seconds)).Err()
 }
 
+func (s *Redis) TrySave(key string, value string, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return s.client.SetNX(key, value, ttl).Result()
+}
+
+func (s *Redis) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	ret, err := s.client.Eval(script, keys, args...).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return ret, err
+}
+
 func (s *Redis) Exists(key string) (bool, error) {
-	b, err := s.client.Exists(key).Result()
+	b, err := s.reader().Exists(key).Result()
 	return b != 0, err
 }
 
@@ -80,6 +143,174 @@ func (s *Redis) Delete(key ...string) error {
 	return s.client.Del(key...).Err()
 }
 
+func (s *Redis) MGet(key ...string) (map[string]string, error) {
+	ret := make(map[string]string)
+	if len(key) == 0 {
+		return ret, nil
+	}
+	vals, err := s.reader().MGet(key...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		if str, ok := v.(string); ok {
+			ret[key[i]] = str
+		}
+	}
+	return ret, nil
+}
+
+func (s *Redis) MSave(values map[string]string, exp_time_seconds int) error {
+	if len(values) == 0 {
+		return nil
+	}
+	pipe := s.client.Pipeline()
+	defer pipe.Close()
+	for k, v := range values {
+		pipe.Set(k, v, time.Second*time.Duration(exp_time_seconds))
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+func (s *Redis) MDelete(key ...string) (int64, error) {
+	if len(key) == 0 {
+		return 0, nil
+	}
+	return s.client.Del(key...).Result()
+}
+
+func (s *Redis) Pipeline() Pipeline {
+	return &redisPipeline{pipe: s.client.Pipeline()}
+}
+
+func (s *Redis) TxPipeline() Pipeline {
+	return &redisPipeline{pipe: s.client.TxPipeline()}
+}
+
+func (s *Redis) HGet(key string, field string) (string, error) {
+	val, err := s.reader().HGet(key, field).Result()
+	if err == redis.Nil {
+		return val, ErrKeyNotFound
+	}
+	return val, err
+}
+
+func (s *Redis) HSet(key string, field string, value string) error {
+	return s.client.HSet(key, field, value).Err()
+}
+
+func (s *Redis) HGetAll(key string) (map[string]string, error) {
+	return s.reader().HGetAll(key).Result()
+}
+
+func (s *Redis) HIncrBy(key string, field string, incValue int64) (int64, error) {
+	return s.client.HIncrBy(key, field, incValue).Result()
+}
+
+func (s *Redis) HDel(key string, field ...string) error {
+	return s.client.HDel(key, field...).Err()
+}
+
+func (s *Redis) LPush(key string, value ...string) (int64, error) {
+	return s.client.LPush(key, toInterfaces(value)...).Result()
+}
+
+func (s *Redis) RPop(key string) (string, error) {
+	val, err := s.client.RPop(key).Result()
+	if err == redis.Nil {
+		return val, ErrKeyNotFound
+	}
+	return val, err
+}
+
+func (s *Redis) LRange(key string, start int64, stop int64) ([]string, error) {
+	return s.reader().LRange(key, start, stop).Result()
+}
+
+func (s *Redis) LLen(key string) (int64, error) {
+	return s.reader().LLen(key).Result()
+}
+
+func (s *Redis) SAdd(key string, member ...string) (int64, error) {
+	return s.client.SAdd(key, toInterfaces(member)...).Result()
+}
+
+func (s *Redis) SRem(key string, member ...string) error {
+	return s.client.SRem(key, toInterfaces(member)...).Err()
+}
+
+func (s *Redis) SMembers(key string) ([]string, error) {
+	return s.reader().SMembers(key).Result()
+}
+
+func (s *Redis) SIsMember(key string, member string) (bool, error) {
+	return s.reader().SIsMember(key, member).Result()
+}
+
+func (s *Redis) ZAdd(key string, member ...Z) (int64, error) {
+	zs := make([]redis.Z, len(member))
+	for i, m := range member {
+		zs[i] = redis.Z{Score: m.Score, Member: m.Member}
+	}
+	return s.client.ZAdd(key, zs...).Result()
+}
+
+func (s *Redis) ZIncrBy(key string, member string, incValue float64) (float64, error) {
+	return s.client.ZIncrBy(key, incValue, member).Result()
+}
+
+func (s *Redis) ZRangeByScore(key string, min string, max string, offset int64, count int64) ([]Z, error) {
+	opt := redis.ZRangeBy{Min: min, Max: max, Offset: offset, Count: count}
+	if count <= 0 {
+		opt.Count = -1
+	}
+	zs, err := s.reader().ZRangeByScoreWithScores(key, opt).Result()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Z, len(zs))
+	for i, z := range zs {
+		ret[i] = Z{Score: z.Score, Member: z.Member.(string)}
+	}
+	return ret, nil
+}
+
+func (s *Redis) ZRevRank(key string, member string) (int64, error) {
+	rank, err := s.reader().ZRevRank(key, member).Result()
+	if err == redis.Nil {
+		return rank, ErrKeyNotFound
+	}
+	return rank, err
+}
+
+func (s *Redis) ZRem(key string, member ...string) error {
+	return s.client.ZRem(key, toInterfaces(member)...).Err()
+}
+
 func (s *Redis) Close() error {
+	if s.replica != nil {
+		s.replica.Close()
+	}
 	return s.client.Close()
 }
+
+func (s *Redis) Publish(channel string, message string) error {
+	return s.client.Publish(channel, message).Err()
+}
+
+// go-redis reconnects the subscription by itself if the connection is lost.
+func (s *Redis) Subscribe(channel string, handler func(message string)) (io.Closer, error) {
+	sub := s.client.Subscribe(channel)
+	// wait for the confirmation so that no message published after return is missed
+	if _, err := sub.Receive(); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	go func() {
+		for msg := range sub.Channel() {
+			handler(msg.Payload)
+		}
+	}()
+	return sub, nil
+}
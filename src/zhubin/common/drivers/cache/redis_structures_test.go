@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestRedis_Structures(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	client := NewRedis(s.Addr(), "", 0)
+	defer client.Close()
+
+	// hash
+	assert.Nil(t, client.HSet("user", "name", "alice"))
+	v, err := client.HGet("user", "name")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", v)
+	_, err = client.HGet("user", "missing")
+	assert.Equal(t, ErrKeyNotFound, err)
+	n, err := client.HIncrBy("user", "visits", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+	all, err := client.HGetAll("user")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"name": "alice", "visits": "2"}, all)
+	assert.Nil(t, client.HDel("user", "visits"))
+	all, err = client.HGetAll("user")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"name": "alice"}, all)
+
+	// list as a queue
+	n, err = client.LPush("queue", "a", "b", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+	l, err := client.LRange("queue", 0, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"c", "b", "a"}, l)
+	v, err = client.RPop("queue")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", v)
+	n, err = client.LLen("queue")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+	client.RPop("queue")
+	client.RPop("queue")
+	_, err = client.RPop("queue")
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	// set
+	n, err = client.SAdd("tags", "x", "y", "x")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+	ok, err := client.SIsMember("tags", "y")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Nil(t, client.SRem("tags", "y"))
+	members, err := client.SMembers("tags")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"x"}, members)
+
+	// sorted set as a leaderboard
+	n, err = client.ZAdd("board", Z{Score: 10, Member: "a"}, Z{Score: 30, Member: "b"}, Z{Score: 20, Member: "c"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+	score, err := client.ZIncrBy("board", "a", 25)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(35), score)
+	rank, err := client.ZRevRank("board", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), rank)
+	_, err = client.ZRevRank("board", "missing")
+	assert.Equal(t, ErrKeyNotFound, err)
+	zs, err := client.ZRangeByScore("board", "-inf", "+inf", 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []Z{{20, "c"}, {30, "b"}, {35, "a"}}, zs)
+	zs, err = client.ZRangeByScore("board", "(20", "+inf", 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []Z{{35, "a"}}, zs)
+	assert.Nil(t, client.ZRem("board", "a", "b"))
+	zs, err = client.ZRangeByScore("board", "-inf", "+inf", 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []Z{{20, "c"}}, zs)
+}
+
+func TestRedis_Scan(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	client := NewRedis(s.Addr(), "", 0)
+	defer client.Close()
+
+	for i := 0; i < 250; i++ {
+		assert.Nil(t, client.Save(fmt.Sprintf("session:%d", i), "v", 0))
+	}
+	assert.Nil(t, client.Save("user:1", "v", 0))
+
+	it := client.Scan("session:*", 10)
+	n := 0
+	for it.Next() {
+		assert.True(t, strings.HasPrefix(it.Key(), "session:"))
+		n++
+	}
+	assert.Nil(t, it.Err())
+	assert.Nil(t, it.Close())
+	assert.Equal(t, 250, n)
+
+	keys, err := client.Keys("user:*")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"user:1"}, keys)
+
+	deleted, err := client.DeleteByPattern("session:*")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(250), deleted)
+	keys, err = client.Keys("*")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"user:1"}, keys)
+}
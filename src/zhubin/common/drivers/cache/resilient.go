@@ -0,0 +1,720 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Returned without calling redis while the circuit breaker is open, or when redis can not be reached.
+var ErrCacheUnavailable = errors.New("cache unavailable")
+
+var errNoPubSub = errors.New("cache does not implement PubSub")
+
+// State of the circuit breaker of a ResilientCache.
+type BreakerState int
+
+const (
+	// Redis is healthy, the commands are sent.
+	BreakerClosed BreakerState = iota
+	// Redis is unhealthy, the commands fail fast with ErrCacheUnavailable.
+	BreakerOpen
+	// A single command is sent to check whether redis is back, the others fail fast.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// Snapshot of the health of a ResilientCache.
+type CacheHealth struct {
+	State     BreakerState
+	Connected bool
+	// Connection failures since the last command which reached redis.
+	ConsecutiveFailures int
+	LastError           error
+	// When State was entered.
+	Since time.Time
+	// When the next attempt is allowed, while the breaker is open.
+	RetryAt time.Time
+}
+
+func (h CacheHealth) Healthy() bool {
+	return h.State == BreakerClosed && h.Connected
+}
+
+// L2Cache which does not take the service down with redis:
+//
+//   - the connection is made by the first command rather than at startup, and retried with an exponential backoff;
+//   - after FailureThreshold consecutive connection failures the breaker opens and the commands fail fast
+//     with ErrCacheUnavailable, until a single command is let through after OpenTimeout to probe redis;
+//   - if there is a fallback, Get, MGet, Save, MSave, Delete and MDelete use it while redis is unavailable.
+//
+// Only the errors of the connection count as failures, e.g. not ErrKeyNotFound or WRONGTYPE.
+// It implements PubSub if the clients returned by connect do.
+// NOTE the values saved in the fallback are not written to redis once it is back, and the other
+// instances do not see them.
+type ResilientCache struct {
+	// Consecutive connection failures opening the breaker, defaults to 5.
+	FailureThreshold int
+	// Time the breaker stays open, doubled after every failed probe up to MaxOpenTimeout.
+	// Default to 1 second and 1 minute.
+	OpenTimeout    time.Duration
+	MaxOpenTimeout time.Duration
+	// How long the values stay in the fallback, capped by their own expiration. 0 means the default expiration of it.
+	FallbackExpiration time.Duration
+	// Called, outside of any lock, after every change of state.
+	OnStateChange func(from BreakerState, to BreakerState)
+
+	connect  func() (L2Cache, error)
+	fallback LocalCache
+
+	mu       sync.Mutex
+	cache    L2Cache
+	dialing  bool
+	state    BreakerState
+	failures int
+	lastErr  error
+	since    time.Time
+	backoff  time.Duration
+	retryAt  time.Time
+	changes  [][2]BreakerState
+}
+
+// connect is called to create the client, e.g. a closure calling DialRedis, until it succeeds.
+// fallback may be nil, it is usually a BoundedL1Cache so that the memory used is limited.
+func NewResilientCache(connect func() (L2Cache, error), fallback LocalCache) *ResilientCache {
+	if connect == nil {
+		panic("Uninitialized cache.")
+	}
+	return &ResilientCache{connect: connect, fallback: fallback, since: time.Now()}
+}
+
+func (c *ResilientCache) Health() CacheHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := CacheHealth{
+		State:               c.state,
+		Connected:           c.cache != nil,
+		ConsecutiveFailures: c.failures,
+		LastError:           c.lastErr,
+		Since:               c.since,
+	}
+	if c.state == BreakerOpen {
+		h.RetryAt = c.retryAt
+	}
+	return h
+}
+
+// Health check endpoint, 200 if redis is healthy and 503 otherwise.
+func (c *ResilientCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h := c.Health()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !h.Healthy() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprintf(w, "state: %s\nconnected: %t\nconsecutive_failures: %d\n", h.State, h.Connected, h.ConsecutiveFailures)
+	if h.LastError != nil {
+		fmt.Fprintf(w, "last_error: %s\n", h.LastError)
+	}
+}
+
+// The client to send a command with, to be followed by a call to release with the error of the command.
+func (c *ResilientCache) acquire() (L2Cache, error) {
+	c.mu.Lock()
+	switch c.state {
+	case BreakerOpen:
+		if time.Now().Before(c.retryAt) {
+			c.mu.Unlock()
+			return nil, ErrCacheUnavailable
+		}
+		// this command is the probe
+		c.transition(BreakerHalfOpen)
+	case BreakerHalfOpen:
+		c.mu.Unlock()
+		return nil, ErrCacheUnavailable
+	}
+	if c.cache != nil {
+		cache := c.cache
+		c.unlock()
+		return cache, nil
+	}
+	if c.dialing {
+		c.unlock()
+		return nil, ErrCacheUnavailable
+	}
+	c.dialing = true
+	c.unlock()
+
+	cache, err := c.connect()
+
+	c.mu.Lock()
+	c.dialing = false
+	if err != nil {
+		c.lastErr = err
+		c.failures++
+		if c.state == BreakerHalfOpen || c.failures >= c.failureThreshold() {
+			c.open()
+		}
+		c.unlock()
+		return nil, ErrCacheUnavailable
+	}
+	c.cache = cache
+	c.unlock()
+	return cache, nil
+}
+
+func (c *ResilientCache) release(err error) {
+	c.mu.Lock()
+	if isConnectionError(err) {
+		c.lastErr = err
+		c.failures++
+		if c.state == BreakerHalfOpen || c.failures >= c.failureThreshold() {
+			c.open()
+		}
+	} else {
+		c.failures = 0
+		if c.state != BreakerClosed {
+			c.transition(BreakerClosed)
+		}
+	}
+	c.unlock()
+}
+
+// must hold mu
+func (c *ResilientCache) open() {
+	if c.state == BreakerHalfOpen && c.backoff > 0 {
+		c.backoff *= 2
+	} else {
+		c.backoff = c.OpenTimeout
+		if c.backoff <= 0 {
+			c.backoff = time.Second
+		}
+	}
+	max := c.MaxOpenTimeout
+	if max <= 0 {
+		max = time.Minute
+	}
+	if c.backoff > max {
+		c.backoff = max
+	}
+	c.retryAt = time.Now().Add(c.backoff)
+	if c.state != BreakerOpen {
+		c.transition(BreakerOpen)
+	}
+}
+
+// must hold mu
+func (c *ResilientCache) transition(to BreakerState) {
+	c.changes = append(c.changes, [2]BreakerState{c.state, to})
+	c.state = to
+	c.since = time.Now()
+	if to == BreakerClosed {
+		c.backoff = 0
+	}
+}
+
+// Unlocks mu, then reports the changes of state.
+func (c *ResilientCache) unlock() {
+	changes := c.changes
+	c.changes = nil
+	lastErr := c.lastErr
+	c.mu.Unlock()
+	for _, change := range changes {
+		entry := logrus.WithFields(logrus.Fields{"from": change[0].String(), "to": change[1].String()})
+		if change[1] == BreakerClosed {
+			entry.Info("l2 cache is available again")
+		} else {
+			entry.WithField("error", fmt.Sprint(lastErr)).Warn("l2 cache is unavailable")
+		}
+		if c.OnStateChange != nil {
+			c.OnStateChange(change[0], change[1])
+		}
+	}
+}
+
+func (c *ResilientCache) failureThreshold() int {
+	if c.FailureThreshold <= 0 {
+		return 5
+	}
+	return c.FailureThreshold
+}
+
+// Errors showing that redis could not be reached, rather than a reply of it.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == ErrCacheUnavailable || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "connection pool timeout") ||
+		strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "use of closed network connection")
+}
+
+func (c *ResilientCache) do(fn func(cache L2Cache) error) error {
+	cache, err := c.acquire()
+	if err != nil {
+		return err
+	}
+	err = fn(cache)
+	c.release(err)
+	return err
+}
+
+// whether to fall back after err
+func (c *ResilientCache) degraded(err error) bool {
+	return c.fallback != nil && isConnectionError(err)
+}
+
+func (c *ResilientCache) fallbackExpiration(exp_time_seconds int) time.Duration {
+	d := c.FallbackExpiration
+	if exp := time.Duration(exp_time_seconds) * time.Second; exp > 0 && (d <= 0 || exp < d) {
+		d = exp
+	}
+	return d
+}
+
+func (c *ResilientCache) Get(key string) (value string, err error) {
+	err = c.do(func(cache L2Cache) error {
+		value, err = cache.Get(key)
+		return err
+	})
+	switch {
+	case c.fallback == nil:
+	case err == nil:
+		c.fallback.Set(key, value, c.fallbackExpiration(0))
+	case err == ErrKeyNotFound:
+		c.fallback.Delete(key)
+	case isConnectionError(err):
+		if v, found := c.fallback.Get(key); found {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+		}
+		return "", ErrKeyNotFound
+	}
+	return value, err
+}
+
+// The keys not found in redis are removed from the fallback.
+func (c *ResilientCache) MGet(key ...string) (ret map[string]string, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.MGet(key...)
+		return err
+	})
+	switch {
+	case c.fallback == nil:
+	case err == nil:
+		for _, k := range key {
+			if v, found := ret[k]; found {
+				c.fallback.Set(k, v, c.fallbackExpiration(0))
+			} else {
+				c.fallback.Delete(k)
+			}
+		}
+	case isConnectionError(err):
+		ret = make(map[string]string)
+		for _, k := range key {
+			if v, found := c.fallback.Get(k); found {
+				if s, ok := v.(string); ok {
+					ret[k] = s
+				}
+			}
+		}
+		return ret, nil
+	}
+	return ret, err
+}
+
+func (c *ResilientCache) Save(key string, value string, exp_time_seconds int) error {
+	err := c.do(func(cache L2Cache) error {
+		return cache.Save(key, value, exp_time_seconds)
+	})
+	if c.fallback != nil && (err == nil || isConnectionError(err)) {
+		c.fallback.Set(key, value, c.fallbackExpiration(exp_time_seconds))
+	}
+	if c.degraded(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *ResilientCache) MSave(values map[string]string, exp_time_seconds int) error {
+	err := c.do(func(cache L2Cache) error {
+		return cache.MSave(values, exp_time_seconds)
+	})
+	if c.fallback != nil && (err == nil || isConnectionError(err)) {
+		for k, v := range values {
+			c.fallback.Set(k, v, c.fallbackExpiration(exp_time_seconds))
+		}
+	}
+	if c.degraded(err) {
+		return nil
+	}
+	return err
+}
+
+// The keys are always removed from the fallback, but the error is returned when redis is unavailable
+// since they are still in redis.
+func (c *ResilientCache) Delete(key ...string) error {
+	if c.fallback != nil {
+		for _, k := range key {
+			c.fallback.Delete(k)
+		}
+	}
+	return c.do(func(cache L2Cache) error {
+		return cache.Delete(key...)
+	})
+}
+
+// Same as Delete.
+func (c *ResilientCache) MDelete(key ...string) (ret int64, err error) {
+	if c.fallback != nil {
+		for _, k := range key {
+			c.fallback.Delete(k)
+		}
+	}
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.MDelete(key...)
+		return err
+	})
+	return ret, err
+}
+
+// The errors of the iteration are not counted by the breaker.
+func (c *ResilientCache) Scan(pattern string, count int) KeyIterator {
+	cache, err := c.acquire()
+	if err != nil {
+		return errKeyIterator{err: err}
+	}
+	c.release(nil)
+	return cache.Scan(pattern, count)
+}
+
+type errKeyIterator struct {
+	err error
+}
+
+func (it errKeyIterator) Next() bool   { return false }
+func (it errKeyIterator) Key() string  { return "" }
+func (it errKeyIterator) Err() error   { return it.err }
+func (it errKeyIterator) Close() error { return nil }
+
+func (c *ResilientCache) Pipeline() Pipeline {
+	return &resilientPipeline{c: c}
+}
+
+func (c *ResilientCache) TxPipeline() Pipeline {
+	return &resilientPipeline{c: c, tx: true}
+}
+
+// The commands are queued here, and sent with a pipeline of the client on Exec.
+type resilientPipeline struct {
+	c    *ResilientCache
+	tx   bool
+	cmds [][]interface{}
+}
+
+func (p *resilientPipeline) Cmd(args ...interface{}) {
+	p.cmds = append(p.cmds, args)
+}
+
+func (p *resilientPipeline) Exec() (replies []interface{}, err error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	err = p.c.do(func(cache L2Cache) error {
+		pipe := cache.Pipeline()
+		if p.tx {
+			pipe = cache.TxPipeline()
+		}
+		for _, args := range cmds {
+			pipe.Cmd(args...)
+		}
+		replies, err = pipe.Exec()
+		return err
+	})
+	return replies, err
+}
+
+// Fails if the client does not implement PubSub.
+func (c *ResilientCache) Publish(channel string, message string) error {
+	return c.do(func(cache L2Cache) error {
+		ps, ok := cache.(PubSub)
+		if !ok {
+			return errNoPubSub
+		}
+		return ps.Publish(channel, message)
+	})
+}
+
+// The subscription is made with the current client, so it fails with ErrCacheUnavailable while redis
+// can not be reached, e.g. NewLayeredCache does then.
+func (c *ResilientCache) Subscribe(channel string, handler func(message string)) (sub io.Closer, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ps, ok := cache.(PubSub)
+		if !ok {
+			return errNoPubSub
+		}
+		sub, err = ps.Subscribe(channel, handler)
+		return err
+	})
+	return sub, err
+}
+
+// Closes the client if connected, the next command connects again.
+func (c *ResilientCache) Close() error {
+	c.mu.Lock()
+	cache := c.cache
+	c.cache = nil
+	c.mu.Unlock()
+	if cache == nil {
+		return nil
+	}
+	return cache.Close()
+}
+
+func (c *ResilientCache) Keys(pattern string) (ret []string, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.Keys(pattern)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) DeleteByPattern(pattern string) (ret int64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.DeleteByPattern(pattern)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) GetObject(key string, out interface{}) error {
+	return c.do(func(cache L2Cache) error {
+		return cache.GetObject(key, out)
+	})
+}
+
+func (c *ResilientCache) SaveObject(key string, value interface{}, exp_time_seconds int) error {
+	return c.do(func(cache L2Cache) error {
+		return cache.SaveObject(key, value, exp_time_seconds)
+	})
+}
+
+func (c *ResilientCache) SaveIfNotExists(key string, value string, exp_time_seconds int) error {
+	return c.do(func(cache L2Cache) error {
+		return cache.SaveIfNotExists(key, value, exp_time_seconds)
+	})
+}
+
+func (c *ResilientCache) SaveObjectIfNotExists(key string, value interface{}, exp_time_seconds int) error {
+	return c.do(func(cache L2Cache) error {
+		return cache.SaveObjectIfNotExists(key, value, exp_time_seconds)
+	})
+}
+
+func (c *ResilientCache) TrySave(key string, value string, ttl time.Duration) (ret bool, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.TrySave(key, value, ttl)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) Eval(script string, keys []string, args ...interface{}) (ret interface{}, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.Eval(script, keys, args...)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) Exists(key string) (ret bool, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.Exists(key)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) SetExpire(key string, exp_time_seconds int) error {
+	return c.do(func(cache L2Cache) error {
+		return cache.SetExpire(key, exp_time_seconds)
+	})
+}
+
+func (c *ResilientCache) Incr(key string) (ret int64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.Incr(key)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) IncrBy(key string, incValue int64) (ret int64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.IncrBy(key, incValue)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) HGet(key string, field string) (ret string, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.HGet(key, field)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) HSet(key string, field string, value string) error {
+	return c.do(func(cache L2Cache) error {
+		return cache.HSet(key, field, value)
+	})
+}
+
+func (c *ResilientCache) HGetAll(key string) (ret map[string]string, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.HGetAll(key)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) HIncrBy(key string, field string, incValue int64) (ret int64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.HIncrBy(key, field, incValue)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) HDel(key string, field ...string) error {
+	return c.do(func(cache L2Cache) error {
+		return cache.HDel(key, field...)
+	})
+}
+
+func (c *ResilientCache) LPush(key string, value ...string) (ret int64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.LPush(key, value...)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) RPop(key string) (ret string, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.RPop(key)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) LRange(key string, start int64, stop int64) (ret []string, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.LRange(key, start, stop)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) LLen(key string) (ret int64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.LLen(key)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) SAdd(key string, member ...string) (ret int64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.SAdd(key, member...)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) SRem(key string, member ...string) error {
+	return c.do(func(cache L2Cache) error {
+		return cache.SRem(key, member...)
+	})
+}
+
+func (c *ResilientCache) SMembers(key string) (ret []string, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.SMembers(key)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) SIsMember(key string, member string) (ret bool, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.SIsMember(key, member)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) ZAdd(key string, member ...Z) (ret int64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.ZAdd(key, member...)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) ZIncrBy(key string, member string, incValue float64) (ret float64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.ZIncrBy(key, member, incValue)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) ZRangeByScore(key string, min string, max string, offset int64, count int64) (ret []Z, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.ZRangeByScore(key, min, max, offset, count)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) ZRevRank(key string, member string) (ret int64, err error) {
+	err = c.do(func(cache L2Cache) error {
+		ret, err = cache.ZRevRank(key, member)
+		return err
+	})
+	return ret, err
+}
+
+func (c *ResilientCache) ZRem(key string, member ...string) error {
+	return c.do(func(cache L2Cache) error {
+		return cache.ZRem(key, member...)
+	})
+}
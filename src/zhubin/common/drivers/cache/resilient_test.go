@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"github.com/alicebob/miniredis"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResilientCache_LazyConnection(t *testing.T) {
+	dials := 0
+	var s *miniredis.Miniredis
+	c := NewResilientCache(func() (L2Cache, error) {
+		dials++
+		if s == nil {
+			return DialRedis("127.0.0.1:1", "", 0)
+		}
+		return DialRedis(s.Addr(), "", 0)
+	}, nil)
+	c.FailureThreshold = 2
+	c.OpenTimeout = 50 * time.Millisecond
+	defer c.Close()
+	assert.Equal(t, 0, dials)
+
+	// the failed connections open the breaker, the next commands fail fast
+	_, err := c.Get("k")
+	assert.Equal(t, ErrCacheUnavailable, err)
+	assert.Equal(t, BreakerClosed, c.Health().State)
+	assert.Equal(t, ErrCacheUnavailable, c.Save("k", "v", 0))
+	assert.Equal(t, ErrCacheUnavailable, c.Save("k", "v", 0))
+	assert.Equal(t, 2, dials)
+	h := c.Health()
+	assert.Equal(t, BreakerOpen, h.State)
+	assert.False(t, h.Connected)
+	assert.NotNil(t, h.LastError)
+
+	// the failed probe doubles the backoff
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.Get("k")
+	assert.Equal(t, ErrCacheUnavailable, err)
+	assert.Equal(t, 3, dials)
+	assert.True(t, c.Health().RetryAt.Sub(time.Now()) > 60*time.Millisecond)
+
+	s, err = miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	time.Sleep(110 * time.Millisecond)
+	assert.Nil(t, c.Save("k", "v", 0))
+	v, err := c.Get("k")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", v)
+	assert.Equal(t, 4, dials)
+	assert.True(t, c.Health().Healthy())
+}
+
+func TestResilientCache_PubSub(t *testing.T) {
+	l2 := newMemL2(&memBus{})
+	newLayered := func() (*LayeredCache, *ResilientCache) {
+		r := NewResilientCache(func() (L2Cache, error) {
+			return l2, nil
+		}, nil)
+		c, err := NewLayeredCache(NewCache(NoExpiration, 0), r, "invalidations")
+		assert.Nil(t, err)
+		return c, r
+	}
+	a, _ := newLayered()
+	b, _ := newLayered()
+
+	assert.Nil(t, a.Save("k", "1", 0))
+	v, err := b.Get("k", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "1", v)
+	assert.Nil(t, a.Save("k", "2", 0))
+	v, err = b.Get("k", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "2", v)
+
+	// through the breaker
+	c := NewResilientCache(func() (L2Cache, error) {
+		return DialRedis("127.0.0.1:1", "", 0)
+	}, nil)
+	c.FailureThreshold = 1
+	c.OpenTimeout = time.Minute
+	_, err = NewLayeredCache(NewCache(NoExpiration, 0), c, "invalidations")
+	assert.Equal(t, ErrCacheUnavailable, err)
+	assert.Equal(t, ErrCacheUnavailable, c.Publish("invalidations", "x"))
+	assert.Equal(t, BreakerOpen, c.Health().State)
+}
+
+func TestResilientCache_Breaker(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	var changes []BreakerState
+	c := NewResilientCache(func() (L2Cache, error) {
+		return DialRedis(s.Addr(), "", 0)
+	}, nil)
+	c.FailureThreshold = 2
+	c.OpenTimeout = 50 * time.Millisecond
+	c.OnStateChange = func(from BreakerState, to BreakerState) {
+		changes = append(changes, to)
+	}
+	defer c.Close()
+
+	assert.Nil(t, c.Save("k", "v", 0))
+	// not a connection error
+	_, err = c.Get("missing")
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.Equal(t, BreakerClosed, c.Health().State)
+
+	s.Close()
+	_, err = c.Get("k")
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrCacheUnavailable, err)
+	assert.Equal(t, BreakerClosed, c.Health().State)
+	_, err = c.Get("k")
+	assert.NotNil(t, err)
+	assert.Equal(t, BreakerOpen, c.Health().State)
+	assert.Equal(t, 2, c.Health().ConsecutiveFailures)
+	_, err = c.Get("k")
+	assert.Equal(t, ErrCacheUnavailable, err)
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, 503, rec.Code)
+
+	assert.Nil(t, s.Restart())
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.Get("missing")
+	assert.Equal(t, ErrKeyNotFound, err)
+	assert.Equal(t, BreakerClosed, c.Health().State)
+	assert.Equal(t, 0, c.Health().ConsecutiveFailures)
+	assert.Equal(t, []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed}, changes)
+
+	rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, 200, rec.Code)
+}
+
+func TestResilientCache_Fallback(t *testing.T) {
+	s, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer s.Close()
+	c := NewResilientCache(func() (L2Cache, error) {
+		return DialRedis(s.Addr(), "", 0)
+	}, NewCache(NoExpiration, 0))
+	c.FailureThreshold = 1
+	c.OpenTimeout = time.Minute
+	defer c.Close()
+
+	assert.Nil(t, c.Save("a", "1", 0))
+	s.Set("b", "2")
+	values, err := c.MGet("b", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"b": "2"}, values)
+
+	s.Close()
+	v, err := c.Get("a")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", v)
+	assert.Equal(t, BreakerOpen, c.Health().State)
+
+	values, err = c.MGet("a", "b", "c")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, values)
+	_, err = c.Get("c")
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	// saved in the fallback only
+	assert.Nil(t, c.Save("d", "4", 0))
+	assert.Nil(t, c.MSave(map[string]string{"e": "5"}, 0))
+	values, err = c.MGet("d", "e")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"d": "4", "e": "5"}, values)
+
+	// not deleted from redis
+	assert.Equal(t, ErrCacheUnavailable, c.Delete("a"))
+	_, err = c.Get("a")
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	// no fallback for the other commands
+	_, err = c.Incr("n")
+	assert.Equal(t, ErrCacheUnavailable, err)
+}
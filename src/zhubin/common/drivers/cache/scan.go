@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"github.com/go-redis/redis"
+	radixCluster "github.com/mediocregopher/radix.v2/cluster"
+	radix "github.com/mediocregopher/radix.v2/redis"
+	"sort"
+)
+
+// number of keys deleted per DEL by DeleteByPattern
+const deleteBatchSize = 100
+
+// Collect the keys of it without duplicates.
+func scanKeys(it KeyIterator) ([]string, error) {
+	defer it.Close()
+	seen := make(map[string]bool)
+	keys := []string{}
+	for it.Next() {
+		if !seen[it.Key()] {
+			seen[it.Key()] = true
+			keys = append(keys, it.Key())
+		}
+	}
+	return keys, it.Err()
+}
+
+// Delete the keys of it in batches with del.
+func deleteKeys(it KeyIterator, del func(keys ...string) (int64, error)) (int64, error) {
+	defer it.Close()
+	var deleted int64
+	batch := make([]string, 0, deleteBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := del(batch...)
+		deleted += n
+		batch = batch[:0]
+		return err
+	}
+	for it.Next() {
+		batch = append(batch, it.Key())
+		if len(batch) == deleteBatchSize {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
+
+type redisKeyIterator struct {
+	*redis.ScanIterator
+}
+
+func (it redisKeyIterator) Key() string {
+	return it.Val()
+}
+
+func (it redisKeyIterator) Close() error {
+	return nil
+}
+
+// Scans the masters one after the other, each with a connection of the pool of the master.
+type clusterKeyIterator struct {
+	cluster *radixCluster.Cluster
+	pattern string
+	count   int
+
+	addrs  []string
+	conns  map[string]*radix.Client
+	cursor string
+	keys   []string
+	key    string
+	err    error
+}
+
+func newClusterKeyIterator(cluster *radixCluster.Cluster, pattern string, count int) *clusterKeyIterator {
+	it := &clusterKeyIterator{cluster: cluster, pattern: pattern, count: count, cursor: "0"}
+	it.conns, it.err = cluster.GetEvery()
+	for addr := range it.conns {
+		it.addrs = append(it.addrs, addr)
+	}
+	sort.Strings(it.addrs)
+	return it
+}
+
+func (it *clusterKeyIterator) Next() bool {
+	for len(it.keys) == 0 {
+		if it.err != nil || len(it.addrs) == 0 {
+			it.Close()
+			return false
+		}
+		it.fetch()
+	}
+	it.key, it.keys = it.keys[0], it.keys[1:]
+	return true
+}
+
+// Fetch the next batch of the current master, moves on to the next master when its cursor is back to 0.
+func (it *clusterKeyIterator) fetch() {
+	args := []interface{}{it.cursor, "MATCH", it.pattern}
+	if it.count > 0 {
+		args = append(args, "COUNT", it.count)
+	}
+	resp := it.conns[it.addrs[0]].Cmd("SCAN", args...)
+	elems, err := resp.Array()
+	if err == nil && len(elems) != 2 {
+		err = radix.ErrRespNil
+	}
+	if err == nil {
+		it.cursor, err = elems[0].Str()
+	}
+	if err == nil {
+		it.keys, err = elems[1].List()
+	}
+	if err != nil {
+		it.err = err
+		return
+	}
+	if it.cursor == "0" {
+		it.cluster.Put(it.conns[it.addrs[0]])
+		delete(it.conns, it.addrs[0])
+		it.addrs = it.addrs[1:]
+	}
+}
+
+func (it *clusterKeyIterator) Key() string {
+	return it.key
+}
+
+func (it *clusterKeyIterator) Err() error {
+	return it.err
+}
+
+func (it *clusterKeyIterator) Close() error {
+	for addr, conn := range it.conns {
+		it.cluster.Put(conn)
+		delete(it.conns, addr)
+	}
+	it.addrs = nil
+	return nil
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"errors"
+	"github.com/go-redis/redis"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+var errNoReplica = errors.New("no replica available")
+
+const sentinelDialTimeout = 5 * time.Second
+
+// create a redis client of the master monitored by the sentinels.
+// The client follows the master on switchover, the commands failing meanwhile are retried.
+// If readFromReplica, the read only commands go to a replica picked at random, the master if there is none,
+// NOTE the replication is asynchronous so what was just written may not be read back.
+func NewRedisSentinel(masterName string, sentinelAddrs []string, password string, dbnum int, readFromReplica bool) *Redis {
+	rs, err := DialRedisSentinel(masterName, sentinelAddrs, password, dbnum, readFromReplica)
+	if err != nil {
+		panic("Failed to connect redis.")
+	}
+	return rs
+}
+
+// Like NewRedisSentinel, but returns an error if the master can not be reached.
+func DialRedisSentinel(masterName string, sentinelAddrs []string, password string, dbnum int, readFromReplica bool) (*Redis, error) {
+	client := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    masterName,
+		SentinelAddrs: sentinelAddrs,
+		Password:      password,
+		DB:            dbnum,
+		MaxRetries:    3,
+	})
+	if _, err := client.Ping().Result(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	rs := &Redis{
+		client: client,
+	}
+	if readFromReplica {
+		// every new connection asks the sentinels again, so that replicas which
+		// are down or promoted are no longer used once their connections fail
+		rs.replica = redis.NewClient(&redis.Options{
+			Addr:       "ReplicaClient",
+			Password:   password,
+			DB:         dbnum,
+			MaxRetries: 3,
+			Dialer: func() (net.Conn, error) {
+				addr, err := sentinelReplicaAddr(masterName, sentinelAddrs)
+				if err != nil {
+					return nil, err
+				}
+				return net.DialTimeout("tcp", addr, sentinelDialTimeout)
+			},
+		})
+	}
+	return rs, nil
+}
+
+// Address of a healthy replica of the master, or of the master if there is none.
+func sentinelReplicaAddr(masterName string, sentinelAddrs []string) (string, error) {
+	var lastErr error = errNoReplica
+	for _, sentinelAddr := range sentinelAddrs {
+		addr, err := askSentinel(sentinelAddr, masterName)
+		if err == nil {
+			return addr, nil
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+func askSentinel(sentinelAddr string, masterName string) (string, error) {
+	sentinel := redis.NewClient(&redis.Options{Addr: sentinelAddr, DialTimeout: sentinelDialTimeout})
+	defer sentinel.Close()
+
+	cmd := redis.NewSliceCmd("sentinel", "slaves", masterName)
+	sentinel.Process(cmd)
+	replicas, err := cmd.Result()
+	if err != nil {
+		return "", err
+	}
+	var addrs []string
+	for _, r := range replicas {
+		fields, ok := r.([]interface{})
+		if !ok {
+			continue
+		}
+		info := make(map[string]string)
+		for i := 0; i+1 < len(fields); i += 2 {
+			k, _ := fields[i].(string)
+			v, _ := fields[i+1].(string)
+			info[k] = v
+		}
+		if strings.Contains(info["flags"], "down") || strings.Contains(info["flags"], "disconnected") ||
+			info["master-link-status"] != "ok" {
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(info["ip"], info["port"]))
+	}
+	if len(addrs) > 0 {
+		return addrs[rand.Intn(len(addrs))], nil
+	}
+
+	masterCmd := redis.NewStringSliceCmd("sentinel", "get-master-addr-by-name", masterName)
+	sentinel.Process(masterCmd)
+	master, err := masterCmd.Result()
+	if err != nil {
+		return "", err
+	}
+	if len(master) != 2 {
+		return "", errNoReplica
+	}
+	return net.JoinHostPort(master[0], master[1]), nil
+}
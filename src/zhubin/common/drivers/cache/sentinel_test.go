@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"github.com/alicebob/miniredis"
+	"github.com/alicebob/miniredis/server"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// answers the sentinel commands used by the clients, with one master and one replica
+func fakeSentinel(t *testing.T, master *miniredis.Miniredis, replica *miniredis.Miniredis) *server.Server {
+	srv, err := server.NewServer("127.0.0.1:0")
+	assert.Nil(t, err)
+	hostPort := func(addr string) (string, string) {
+		host, port, _ := net.SplitHostPort(addr)
+		return host, port
+	}
+	srv.Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		switch strings.ToLower(args[0]) {
+		case "get-master-addr-by-name":
+			host, port := hostPort(master.Addr())
+			c.WriteLen(2)
+			c.WriteBulk(host)
+			c.WriteBulk(port)
+		case "slaves":
+			host, port := hostPort(replica.Addr())
+			fields := []string{"ip", host, "port", port, "flags", "slave", "master-link-status", "ok"}
+			c.WriteLen(1)
+			c.WriteLen(len(fields))
+			for _, f := range fields {
+				c.WriteBulk(f)
+			}
+		default:
+			c.WriteLen(0)
+		}
+	})
+	srv.Register("SUBSCRIBE", func(c *server.Peer, cmd string, args []string) {
+		c.WriteLen(3)
+		c.WriteBulk("subscribe")
+		c.WriteBulk(args[0])
+		c.WriteInt(1)
+	})
+	return srv
+}
+
+func TestRedisSentinel(t *testing.T) {
+	master, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer master.Close()
+	replica, err := miniredis.Run()
+	assert.Nil(t, err)
+	defer replica.Close()
+	sentinel := fakeSentinel(t, master, replica)
+	defer sentinel.Close()
+	sentinelAddr := "127.0.0.1:" + strconv.Itoa(sentinel.Addr().Port)
+
+	client := NewRedisSentinel("mymaster", []string{sentinelAddr}, "", 0, false)
+	assert.Nil(t, client.Save("key", "master", 0))
+	v, err := client.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "master", v)
+	client.Close()
+
+	// reads go to the replica, writes to the master
+	replica.Set("key", "replica")
+	client = NewRedisSentinel("mymaster", []string{sentinelAddr}, "", 0, true)
+	defer client.Close()
+	v, err = client.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "replica", v)
+	assert.Nil(t, client.Save("other", "v", 0))
+	v, err = master.Get("other")
+	assert.Nil(t, err)
+	assert.Equal(t, "v", v)
+}
@@ -10,4 +10,31 @@ type MySQLConnInfo struct {
 	Port    string `yaml:"port" bson:"port"`
 	Dbname  string `yaml:"dbname" bson:"dbname"`
 	Charset string `yaml:"charset" bson:"charset"`
+	// mysql (default), or sqlite3 for the tests, see sqlite.go.
+	Dialect string `yaml:"dialect" bson:"dialect"`
+	// Directory of the SQL migrations applied on startup, see LoadMigrations. Empty disables.
+	MigrationsDir string `yaml:"migrations_dir" bson:"migrations_dir"`
+
+	// Pool limits of every connection, 0 keeps the defaults of database/sql.
+	MaxOpenConns           int `yaml:"max_open_conns" bson:"max_open_conns"`
+	MaxIdleConns           int `yaml:"max_idle_conns" bson:"max_idle_conns"`
+	ConnMaxLifetimeSeconds int `yaml:"conn_max_lifetime_seconds" bson:"conn_max_lifetime_seconds"`
+
+	// Read only copies of the database, with the same user and dbname, see MySQLDB.Reader.
+	Replicas []MySQLReplica `yaml:"replicas" bson:"replicas"`
+	// round_robin (default) or least_latency
+	ReplicaPolicy string `yaml:"replica_policy" bson:"replica_policy"`
+	// Interval of the pings evicting the unreachable replicas, 10 by default.
+	HealthCheckSeconds int `yaml:"health_check_seconds" bson:"health_check_seconds"`
+
+	// Query log, see QueryTracer.
+	TraceQueries         bool     `yaml:"trace_queries" bson:"trace_queries"` // every statement at Debug level
+	SlowQueryMs          int      `yaml:"slow_query_ms" bson:"slow_query_ms"` // 0 disables
+	TraceSamplePerSecond int      `yaml:"trace_sample_per_second" bson:"trace_sample_per_second"`
+	RedactColumns        []string `yaml:"redact_columns" bson:"redact_columns"`
+}
+
+type MySQLReplica struct {
+	Host string `yaml:"host" bson:"host"`
+	Port string `yaml:"port" bson:"port"`
 }
@@ -0,0 +1,144 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-gorp/gorp"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// a table and its rows, as ordered in the file
+type fixture struct {
+	table string
+	rows  []yaml.MapSlice
+}
+
+// LoadFixtures seeds the database with the rows of YAML files, e.g. in tests:
+//
+//	users:
+//	  - id: 1
+//	    name: alice
+//	roles:
+//	  - user_id: 1
+//	    role: admin
+//
+// The tables are emptied in the reverse order, then filled in order, all in a single transaction.
+// A path may be a directory, whose .yml and .yaml files are loaded in the order of their names.
+func (m *MySQLDB) LoadFixtures(path ...string) error {
+	var fixtures []fixture
+	for _, p := range path {
+		files, err := fixtureFiles(p)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			loaded, err := readFixtures(f)
+			if err != nil {
+				return err
+			}
+			fixtures = append(fixtures, loaded...)
+		}
+	}
+	dialect := m.Db.Dialect
+	return m.WithTx(context.Background(), func(tx *gorp.Transaction) error {
+		emptied := make(map[string]bool)
+		for i := len(fixtures) - 1; i >= 0; i-- {
+			table := fixtures[i].table
+			if emptied[table] {
+				continue
+			}
+			emptied[table] = true
+			if _, err := tx.Exec("DELETE FROM " + dialect.QuotedTableForQuery("", table)); err != nil {
+				return fmt.Errorf("failed to empty %s: %v", table, err)
+			}
+		}
+		for _, f := range fixtures {
+			for _, row := range f.rows {
+				columns := make([]string, len(row))
+				binds := make([]string, len(row))
+				values := make([]interface{}, len(row))
+				for i, item := range row {
+					columns[i] = dialect.QuoteField(fmt.Sprint(item.Key))
+					binds[i] = dialect.BindVar(i)
+					values[i] = item.Value
+				}
+				query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", dialect.QuotedTableForQuery("", f.table),
+					strings.Join(columns, ", "), strings.Join(binds, ", "))
+				if _, err := tx.Exec(query, values...); err != nil {
+					return fmt.Errorf("failed to insert into %s: %v", f.table, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if !e.IsDir() && (ext == ".yml" || ext == ".yaml") {
+			files = append(files, filepath.Join(path, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func readFixtures(file string) ([]fixture, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var tables yaml.MapSlice
+	if err := yaml.Unmarshal(content, &tables); err != nil {
+		return nil, fmt.Errorf("invalid fixtures %s: %v", file, err)
+	}
+	fixtures := make([]fixture, 0, len(tables))
+	for _, t := range tables {
+		f := fixture{table: fmt.Sprint(t.Key)}
+		rows, ok := t.Value.([]interface{})
+		if !ok && t.Value != nil {
+			return nil, fmt.Errorf("invalid fixtures %s: the rows of %s are not a list", file, f.table)
+		}
+		for _, r := range rows {
+			row, ok := r.(yaml.MapSlice)
+			if !ok {
+				return nil, fmt.Errorf("invalid fixtures %s: a row of %s is not a map", file, f.table)
+			}
+			for _, item := range row {
+				if !isFixtureValue(item.Value) {
+					return nil, fmt.Errorf("invalid fixtures %s: %s.%v is not a scalar", file, f.table, item.Key)
+				}
+			}
+			f.rows = append(f.rows, row)
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+func isFixtureValue(v interface{}) bool {
+	switch v.(type) {
+	case nil, string, int, int64, uint64, float64, bool, time.Time:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,422 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/go-gorp/gorp"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const schemaMigrationsTable = "schema_migrations"
+
+var ErrMigrationLocked = errors.New("migrations are locked by another process")
+
+// A versioned schema change. Up and Down run in a transaction which also records the version.
+// NOTE DDL statements commit implicitly in MySQL, so a migration failing after one of them
+// is not rolled back completely: keep a single DDL statement per migration when possible.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorp.Transaction) error
+	// nil if the migration can not be rolled back.
+	Down func(tx *gorp.Transaction) error
+}
+
+// Migration running SQL scripts, which may contain several statements separated by ';'.
+// down may be empty if the migration can not be rolled back.
+func SQLMigration(version int64, name string, up string, down string) *Migration {
+	m := &Migration{Version: version, Name: name, Up: execScript(up)}
+	if strings.TrimSpace(down) != "" {
+		m.Down = execScript(down)
+	}
+	return m
+}
+
+func execScript(script string) func(tx *gorp.Transaction) error {
+	return func(tx *gorp.Transaction) error {
+		for _, stmt := range splitStatements(script) {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// <version>_<name>.up.sql and <version>_<name>.down.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Loads the SQL migrations of dir, named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// e.g. 20180102150405_create_users.up.sql. The down file is optional.
+func LoadMigrations(dir string) ([]*Migration, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type scripts struct {
+		name, up, down string
+		hasUp          bool
+	}
+	byVersion := make(map[int64]*scripts)
+	for _, f := range files {
+		match := migrationFile.FindStringSubmatch(f.Name())
+		if f.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %v", f.Name(), err)
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		s, ok := byVersion[version]
+		if !ok {
+			s = &scripts{name: match[2]}
+			byVersion[version] = s
+		} else if s.name != match[2] {
+			return nil, fmt.Errorf("migration version %d used by both %s and %s", version, s.name, match[2])
+		}
+		if match[3] == "up" {
+			s.up, s.hasUp = string(content), true
+		} else {
+			s.down = string(content)
+		}
+	}
+	migrations := make([]*Migration, 0, len(byVersion))
+	for version, s := range byVersion {
+		if !s.hasUp {
+			return nil, fmt.Errorf("migration %d_%s has no up file", version, s.name)
+		}
+		migrations = append(migrations, SQLMigration(version, s.name, s.up, s.down))
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Splits a script on the ';' ending the statements, ignoring the ones in quotes and comments.
+func splitStatements(script string) []string {
+	var stmts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '#' || (c == '-' && strings.HasPrefix(script[i:], "-- ")):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+		case c == ';':
+			stmts = appendStatement(stmts, script[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(script) {
+		stmts = appendStatement(stmts, script[start:])
+	}
+	return stmts
+}
+
+// without the leading comments, skips the statements made of blanks and comments only
+func appendStatement(stmts []string, stmt string) []string {
+	rest := strings.TrimSpace(stmt)
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "#") || strings.HasPrefix(rest, "-- ") || rest == "--":
+			if end := strings.IndexByte(rest, '\n'); end >= 0 {
+				rest = strings.TrimSpace(rest[end:])
+			} else {
+				rest = ""
+			}
+		case strings.HasPrefix(rest, "/*"):
+			if end := strings.Index(rest, "*/"); end >= 0 {
+				rest = strings.TrimSpace(rest[end+2:])
+			} else {
+				rest = ""
+			}
+		default:
+			return append(stmts, rest)
+		}
+	}
+	return stmts
+}
+
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	Applied bool
+	// zero if not applied
+	AppliedAt time.Time
+	// Applied but not among the known migrations, e.g. by a newer release.
+	Unknown bool
+}
+
+// Applies the migrations and keeps track of them in the schema_migrations table:
+//
+//	migrations, err := mysql.LoadMigrations("migrations")
+//	...
+//	applied, err := mysql.NewMigrator(db, migrations...).Up()
+//
+// A lock is held while migrating, so that the instances starting together do not race.
+type Migrator struct {
+	// Log the migrations which would be applied or rolled back, without running them.
+	DryRun bool
+	// How long to wait for the lock held by another process, defaults to 1 minute.
+	LockTimeout time.Duration
+
+	db         *MySQLDB
+	migrations []*Migration
+}
+
+func NewMigrator(db *MySQLDB, migrations ...*Migration) *Migrator {
+	sorted := append([]*Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+// Applies the pending migrations in order, returns the ones applied.
+// A migration older than the last one applied is applied as well, e.g. after a merge.
+func (m *Migrator) Up() ([]*Migration, error) {
+	return m.UpTo(-1)
+}
+
+// Like Up, but stops after version, all of them if version < 0.
+func (m *Migrator) UpTo(version int64) ([]*Migration, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	var done []*Migration
+	err := m.locked(func() error {
+		applied, err := m.applied()
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if version >= 0 && mg.Version > version {
+				break
+			}
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			if err := m.run(mg, true); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Rolls back the last steps migrations applied, returns the ones rolled back.
+func (m *Migrator) Down(steps int) ([]*Migration, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration, len(m.migrations))
+	for _, mg := range m.migrations {
+		byVersion[mg.Version] = mg
+	}
+	var done []*Migration
+	err := m.locked(func() error {
+		applied, err := m.applied()
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		for i := 0; i < steps && i < len(versions); i++ {
+			mg, ok := byVersion[versions[i]]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but unknown", versions[i])
+			}
+			if mg.Down == nil {
+				return fmt.Errorf("migration %d_%s can not be rolled back", mg.Version, mg.Name)
+			}
+			if err := m.run(mg, false); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// The known migrations and the unknown ones applied, ordered by version.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var status []MigrationStatus
+	for _, mg := range m.migrations {
+		s := MigrationStatus{Version: mg.Version, Name: mg.Name}
+		if r, ok := applied[mg.Version]; ok {
+			s.Applied, s.AppliedAt = true, r.AppliedAt
+			delete(applied, mg.Version)
+		}
+		status = append(status, s)
+	}
+	for _, r := range applied {
+		status = append(status, MigrationStatus{Version: r.Version, Name: r.Name, Applied: true, AppliedAt: r.AppliedAt, Unknown: true})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, nil
+}
+
+func (m *Migrator) validate() error {
+	for i, mg := range m.migrations {
+		if mg.Up == nil {
+			return fmt.Errorf("migration %d_%s has no up", mg.Version, mg.Name)
+		}
+		if i > 0 && m.migrations[i-1].Version == mg.Version {
+			return fmt.Errorf("migration version %d used by both %s and %s", mg.Version, m.migrations[i-1].Name, mg.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) run(mg *Migration, up bool) error {
+	direction, fn := "up", mg.Up
+	if !up {
+		direction, fn = "down", mg.Down
+	}
+	if m.DryRun {
+		logrus.Infof("Migration %d_%s %s (dry run)", mg.Version, mg.Name, direction)
+		return nil
+	}
+	logrus.Infof("Migration %d_%s %s", mg.Version, mg.Name, direction)
+	started := time.Now()
+	tx, err := m.db.Db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s %s failed: %v", mg.Version, mg.Name, direction, err)
+	}
+	if up {
+		_, err = tx.Exec("INSERT INTO "+schemaMigrationsTable+" (version, name, applied_at) VALUES (?, ?, ?)", mg.Version, mg.Name, time.Now().UTC())
+	} else {
+		_, err = tx.Exec("DELETE FROM "+schemaMigrationsTable+" WHERE version = ?", mg.Version)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	logrus.Infof("Migration %d_%s %s done in %v", mg.Version, mg.Name, direction, time.Since(started))
+	return nil
+}
+
+type migrationRecord struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// the migrations applied by version, none if the table does not exist yet
+func (m *Migrator) applied() (map[int64]migrationRecord, error) {
+	var records []migrationRecord
+	_, err := m.db.Db.Select(&records, "SELECT version, name, applied_at FROM "+schemaMigrationsTable)
+	if e := IsMySQLError(err); e != nil && e.Number == ER_NO_SUCH_TABLE {
+		err = nil
+	} else if err != nil && m.db.isSQLite() && strings.HasPrefix(err.Error(), "no such table") {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]migrationRecord, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// Runs fn holding a named lock of the server, after creating the schema_migrations table if needed.
+// The lock belongs to a connection, which is kept out of the pool meanwhile, so the pool is allowed
+// a second connection for fn if it is limited to one.
+func (m *Migrator) locked(fn func() error) error {
+	if m.DryRun {
+		return fn()
+	}
+	if err := m.createTable(); err != nil {
+		return err
+	}
+	// a SQLite database is not shared between processes
+	if m.db.isSQLite() {
+		return fn()
+	}
+	timeout := m.LockTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	db := m.db.Db.Db
+	if max := db.Stats().MaxOpenConnections; max == 1 {
+		db.SetMaxOpenConns(2)
+		defer db.SetMaxOpenConns(max)
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.lockName(), int(timeout.Seconds())).Scan(&got); err != nil {
+		return err
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return ErrMigrationLocked
+	}
+	defer conn.ExecContext(ctx, "DO RELEASE_LOCK(?)", m.lockName())
+	return fn()
+}
+
+func (m *Migrator) createTable() error {
+	_, err := m.db.Db.Exec("CREATE TABLE IF NOT EXISTS " + schemaMigrationsTable + ` (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at DATETIME NOT NULL
+)`)
+	return err
+}
+
+// the locks are global to the server, so the name includes the database
+func (m *Migrator) lockName() string {
+	return m.db.para.Dbname + "." + schemaMigrationsTable
+}
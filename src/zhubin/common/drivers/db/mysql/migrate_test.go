@@ -0,0 +1,258 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/go-gorp/gorp"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `
+-- users
+CREATE TABLE users (
+	id BIGINT NOT NULL PRIMARY KEY, # the id
+	name VARCHAR(64) NOT NULL DEFAULT 'a;b'
+);
+/* seed; */
+INSERT INTO users VALUES (1, "it\"s;"), (2, ` + "`x;`" + `);
+-- trailing comment;
+UPDATE users SET name = 'c' WHERE id = 1`
+	stmts := splitStatements(script)
+	assert.Equal(t, 3, len(stmts))
+	assert.Contains(t, stmts[0], "DEFAULT 'a;b'")
+	assert.Contains(t, stmts[1], "INSERT INTO users")
+	assert.Contains(t, stmts[1], "`x;`")
+	assert.Equal(t, "UPDATE users SET name = 'c' WHERE id = 1", stmts[2])
+
+	assert.Equal(t, 0, len(splitStatements("  ;\n-- nothing\n;")))
+}
+
+func TestLoadMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrations")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"2_add_email.up.sql":      "ALTER TABLE users ADD email VARCHAR(255);",
+		"1_create_users.up.sql":   "CREATE TABLE users (id BIGINT);",
+		"1_create_users.down.sql": "DROP TABLE users;",
+		"README.md":               "not a migration",
+	}
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	migrations, err := LoadMigrations(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(migrations))
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.NotNil(t, migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Nil(t, migrations[1].Down)
+
+	// down without up
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "3_orphan.down.sql"), []byte("SELECT 1"), 0644))
+	_, err = LoadMigrations(dir)
+	assert.NotNil(t, err)
+}
+
+func TestMigrator_Validate(t *testing.T) {
+	m := NewMigrator(nil, SQLMigration(2, "b", "SELECT 1", ""), SQLMigration(1, "a", "SELECT 1", ""), SQLMigration(2, "c", "SELECT 1", ""))
+	_, err := m.Up()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "version 2")
+}
+
+// server understanding the statements of Migrator, the others are only recorded
+type fakeMySQL struct {
+	mu       sync.Mutex
+	lockedBy *fakeMySQLConn
+	records  map[int64]migrationRecord
+	executed []string
+}
+
+func (s *fakeMySQL) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeMySQLConn{server: s}, nil
+}
+
+func (s *fakeMySQL) Driver() driver.Driver { return nil }
+
+type fakeMySQLConn struct {
+	server *fakeMySQL
+}
+
+func (c *fakeMySQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeMySQLStmt{conn: c, query: query}, nil
+}
+func (c *fakeMySQLConn) Close() error              { return nil }
+func (c *fakeMySQLConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeMySQLConn) Commit() error             { return nil }
+func (c *fakeMySQLConn) Rollback() error           { return nil }
+
+type fakeMySQLStmt struct {
+	conn  *fakeMySQLConn
+	query string
+}
+
+func (st *fakeMySQLStmt) Close() error  { return nil }
+func (st *fakeMySQLStmt) NumInput() int { return -1 }
+
+func (st *fakeMySQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s := st.conn.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case strings.HasPrefix(st.query, "DO RELEASE_LOCK"):
+		if s.lockedBy == st.conn {
+			s.lockedBy = nil
+		}
+	case strings.HasPrefix(st.query, "INSERT INTO "+schemaMigrationsTable):
+		s.records[args[0].(int64)] = migrationRecord{Version: args[0].(int64), Name: args[1].(string), AppliedAt: args[2].(time.Time)}
+	case strings.HasPrefix(st.query, "DELETE FROM "+schemaMigrationsTable):
+		delete(s.records, args[0].(int64))
+	case strings.HasPrefix(st.query, "CREATE TABLE IF NOT EXISTS "+schemaMigrationsTable):
+	default:
+		s.executed = append(s.executed, st.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (st *fakeMySQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s := st.conn.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := &fakeMySQLRows{}
+	switch {
+	case strings.HasPrefix(st.query, "SELECT GET_LOCK"):
+		got := int64(0)
+		if s.lockedBy == nil || s.lockedBy == st.conn {
+			s.lockedBy, got = st.conn, 1
+		}
+		rows.columns = []string{"got"}
+		rows.values = [][]driver.Value{{got}}
+	case strings.HasPrefix(st.query, "SELECT version, name, applied_at FROM "+schemaMigrationsTable):
+		rows.columns = []string{"version", "name", "applied_at"}
+		for _, r := range s.records {
+			rows.values = append(rows.values, []driver.Value{r.Version, r.Name, r.AppliedAt})
+		}
+	default:
+		return nil, errors.New("unexpected query " + st.query)
+	}
+	return rows, nil
+}
+
+type fakeMySQLRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeMySQLRows) Columns() []string { return r.columns }
+func (r *fakeMySQLRows) Close() error      { return nil }
+func (r *fakeMySQLRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// a pool of a single connection, as with max_open_conns: 1
+func newFakeMySQLDB() (*MySQLDB, *fakeMySQL) {
+	server := &fakeMySQL{records: make(map[int64]migrationRecord)}
+	db := sql.OpenDB(server)
+	db.SetMaxOpenConns(1)
+	m := &MySQLDB{para: &MySQLConnInfo{Dbname: "test"}}
+	m.Db = gorp.DbMap{Db: db, Dialect: gorp.MySQLDialect{"InnoDB", "utf8"}}
+	return m, server
+}
+
+// fails instead of hanging if the migrations wait for a connection
+func withTimeout(t *testing.T, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	db, server := newFakeMySQLDB()
+	defer db.Close()
+	migrator := NewMigrator(db,
+		SQLMigration(1, "create_users", "CREATE TABLE users (id BIGINT)", "DROP TABLE users"),
+		SQLMigration(2, "add_email", "ALTER TABLE users ADD email VARCHAR(255)", ""))
+
+	withTimeout(t, func() {
+		migrator.DryRun = true
+		applied, err := migrator.Up()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+		assert.Equal(t, 0, len(server.executed))
+		migrator.DryRun = false
+
+		applied, err = migrator.UpTo(1)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(applied))
+		applied, err = migrator.Up()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(applied))
+		assert.Equal(t, int64(2), applied[0].Version)
+		assert.Equal(t, []string{"CREATE TABLE users (id BIGINT)", "ALTER TABLE users ADD email VARCHAR(255)"}, server.executed)
+		assert.Nil(t, server.lockedBy)
+
+		status, err := migrator.Status()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(status))
+		assert.True(t, status[0].Applied && status[1].Applied)
+		assert.False(t, status[1].AppliedAt.IsZero())
+
+		// no down
+		_, err = migrator.Down(1)
+		assert.NotNil(t, err)
+		server.records[3] = migrationRecord{Version: 3, Name: "newer", AppliedAt: time.Now()}
+		status, err = migrator.Status()
+		assert.Nil(t, err)
+		assert.True(t, status[2].Unknown)
+		_, err = migrator.Down(1)
+		assert.NotNil(t, err)
+		delete(server.records, 3)
+		delete(server.records, 2)
+
+		rolledBack, err := migrator.Down(1)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(rolledBack))
+		assert.Equal(t, "DROP TABLE users", server.executed[len(server.executed)-1])
+		status, err = migrator.Status()
+		assert.Nil(t, err)
+		assert.False(t, status[0].Applied)
+	})
+	assert.Equal(t, 1, db.Db.Db.Stats().MaxOpenConnections)
+}
+
+func TestMigrator_Locked(t *testing.T) {
+	db, server := newFakeMySQLDB()
+	defer db.Close()
+	server.lockedBy = &fakeMySQLConn{server: server}
+	withTimeout(t, func() {
+		_, err := NewMigrator(db, SQLMigration(1, "a", "SELECT 1", "")).Up()
+		assert.Equal(t, ErrMigrationLocked, err)
+	})
+	assert.Equal(t, 0, len(server.records))
+}
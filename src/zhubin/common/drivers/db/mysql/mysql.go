@@ -6,22 +6,41 @@ import (
 	"github.com/go-gorp/gorp"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
 )
 
 // defines mysql connection parameters and the client. It implements repository
 // interface so it can be pooling.
+// Db is the primary, MySQLDB itself is a gorp.SqlExecutor sending the reads to the replicas, see replica.go.
 type MySQLDB struct {
 	para *MySQLConnInfo
 	Db   gorp.DbMap
+	// Retries of WithTx after a deadlock or a lock wait timeout, and the delay before the first one,
+	// doubled for each of the next ones.
+	TxRetries    int
+	TxRetryDelay time.Duration
+	// Logs the statements, nil unless enabled by the config.
+	Tracer *QueryTracer
+
+	mu       sync.RWMutex
+	replicas []*replica
+	next     uint32
+	stop     chan struct{}
 }
 
 func NewMySQLDb(conf *MySQLConnInfo) (*MySQLDB, error) {
 	logrus.Info(fmt.Sprintf("Initializing: connecting to mysql instance '%s', host: %s, port: %s", conf.Dbname, conf.Host, conf.Port))
 	// init single mysql instance
-	m := &MySQLDB{para: conf}
+	m := &MySQLDB{para: conf, TxRetries: 3, TxRetryDelay: 20 * time.Millisecond}
 	if err := m.init(); err != nil {
 		return nil, err
 	}
+	if conf.MigrationsDir != "" {
+		if err := m.migrate(conf.MigrationsDir); err != nil {
+			return nil, err
+		}
+	}
 	return m, nil
 }
 
@@ -30,30 +49,113 @@ func (m *MySQLDB) Ping() error {
 	return m.Db.Db.Ping()
 }
 
+// close the connections of the primary and the replicas.
+func (m *MySQLDB) Close() error {
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+	for _, r := range m.replicas {
+		r.db.Close()
+	}
+	return m.Db.Db.Close()
+}
+
 // generate the connection URI.
 func (m *MySQLDB) uri() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=True", m.para.Uname, m.para.Passwd, m.para.Host, m.para.Port, m.para.Dbname)
+	if m.isSQLite() {
+		return m.sqliteURI()
+	}
+	return m.hostURI(m.para.Host, m.para.Port)
+}
+
+func (m *MySQLDB) hostURI(host string, port string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=True", m.para.Uname, m.para.Passwd, host, port, m.para.Dbname)
+}
+
+// apply the pool limits of the config.
+func (m *MySQLDB) configurePool(db *sql.DB) {
+	if m.para.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(m.para.MaxOpenConns)
+	}
+	if m.para.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(m.para.MaxIdleConns)
+	}
+	if m.para.ConnMaxLifetimeSeconds > 0 {
+		db.SetConnMaxLifetime(time.Duration(m.para.ConnMaxLifetimeSeconds) * time.Second)
+	}
+}
+
+// open a connection pool, traced if there is a tracer.
+func (m *MySQLDB) open(dsn string) (*sql.DB, error) {
+	driverName := DialectMySQL
+	if m.isSQLite() {
+		driverName = DialectSQLite
+	}
+	var db *sql.DB
+	if m.Tracer != nil {
+		drv, err := registeredDriver(driverName)
+		if err != nil {
+			return nil, err
+		}
+		db = sql.OpenDB(&tracedConnector{dsn: dsn, driver: drv, tracer: m.Tracer})
+	} else {
+		var err error
+		if db, err = sql.Open(driverName, dsn); err != nil {
+			return nil, err
+		}
+	}
+	m.configurePool(db)
+	return db, nil
 }
 
 // initialize mysql instance against given MySQLConnInfo.
 func (m *MySQLDB) init() error {
+	switch m.para.Dialect {
+	case "", DialectMySQL, DialectSQLite:
+	default:
+		return fmt.Errorf("unknown dialect %q", m.para.Dialect)
+	}
 	// connect to db using standard Go database/sql API
-	db, err := sql.Open("mysql", m.uri())
+	if m.para.TraceQueries || m.para.SlowQueryMs > 0 {
+		m.Tracer = newQueryTracer(m.para)
+	}
+	db, err := m.open(m.uri())
 	if err != nil {
 		return fmt.Errorf("failed to connect MySQL database %s, message: %v", m.para.Host, err)
 	}
+	if m.Tracer != nil {
+		m.Tracer.db = db
+	}
 	// construct a gorp DbMap
-	m.Db = gorp.DbMap{Db: db, Dialect: gorp.MySQLDialect{"InnoDB", m.para.Charset}}
-	m.Db.TraceOn("[gorp]", logrus.StandardLogger())
+	if m.isSQLite() {
+		if err := initSQLite(db); err != nil {
+			return fmt.Errorf("failed to open SQLite database %s, message: %v", m.para.Dbname, err)
+		}
+		m.Db = gorp.DbMap{Db: db, Dialect: gorp.SqliteDialect{}}
+	} else {
+		m.Db = gorp.DbMap{Db: db, Dialect: gorp.MySQLDialect{"InnoDB", m.para.Charset}}
+	}
 
 	// add a table, setting the table name to 'posts' and
 	// specifying that the Id property is an auto incrementing PK
 	//dbmap.AddTableWithName(Post{}, "posts").SetKeys(true, "Id")
 
-	// create the table. in a production system you'd generally
-	// use a migration tool, or create the tables via scripts
-	//err = dbmap.CreateTablesIfNotExists()
-	//checkErr(err, "Create tables failed")
+	// the tables are created by the migrations, see migrate.go
+
+	return m.initReplicas()
+}
 
+// apply the pending migrations of dir.
+func (m *MySQLDB) migrate(dir string) error {
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations from %s, message: %v", dir, err)
+	}
+	applied, err := NewMigrator(m, migrations...).Up()
+	if err != nil {
+		return err
+	}
+	logrus.Info(fmt.Sprintf("Migrations: %d applied to mysql instance '%s'", len(applied), m.para.Dbname))
 	return nil
 }
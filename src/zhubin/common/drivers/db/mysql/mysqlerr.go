@@ -1,6 +1,9 @@
 package mysql
 
-import "github.com/go-sql-driver/mysql"
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+)
 
 // check if it is mysql error.
 // return nil if not.
@@ -11,6 +14,74 @@ func IsMySQLError(err error) *mysql.MySQLError {
 	return nil
 }
 
+// Kinds of errors, see ErrorKind.
+var (
+	ErrDuplicateKey        = errors.New("duplicate key")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrDeadlock            = errors.New("deadlock")
+	ErrLockWaitTimeout     = errors.New("lock wait timeout")
+	ErrConnectionLost      = errors.New("connection lost")
+)
+
+// Returns the kind of err, one of the errors above, or nil if it is none of them.
+func ErrorKind(err error) error {
+	if e, ok := err.(*kindError); ok {
+		return e.kind
+	}
+	if isConnectionError(err) {
+		return ErrConnectionLost
+	}
+	e := IsMySQLError(err)
+	if e == nil {
+		return sqliteErrorKind(err)
+	}
+	switch e.Number {
+	case ER_DUP_ENTRY, ER_DUP_KEY, ER_DUP_ENTRY_WITH_KEY_NAME:
+		return ErrDuplicateKey
+	case ER_NO_REFERENCED_ROW, ER_NO_REFERENCED_ROW_2, ER_ROW_IS_REFERENCED, ER_ROW_IS_REFERENCED_2:
+		return ErrForeignKeyViolation
+	case ER_LOCK_DEADLOCK:
+		return ErrDeadlock
+	case ER_LOCK_WAIT_TIMEOUT:
+		return ErrLockWaitTimeout
+	}
+	return nil
+}
+
+// Wraps err with its kind, so that the message of the server is kept:
+//
+//	if mysql.ErrorKind(err) == mysql.ErrDuplicateKey {
+//
+// It is unchanged if it is of no kind.
+func Classify(err error) error {
+	kind := ErrorKind(err)
+	if kind == nil {
+		return err
+	}
+	if _, ok := err.(*kindError); ok {
+		return err
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+// for errors.Is and errors.As
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
 // MySQL Server Error Constants
 // From https://github.com/VividCortex/mysqlerr
 const (
@@ -0,0 +1,280 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/go-gorp/gorp"
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ReplicaRoundRobin   = "round_robin"
+	ReplicaLeastLatency = "least_latency"
+
+	replicaPingTimeout = 2 * time.Second
+)
+
+// read only copy of the database
+type replica struct {
+	addr    string
+	db      *sql.DB
+	healthy bool
+	// moving average of the ping time
+	latency time.Duration
+}
+
+// open the replicas of the config and start checking their health.
+func (m *MySQLDB) initReplicas() error {
+	switch m.para.ReplicaPolicy {
+	case "", ReplicaRoundRobin, ReplicaLeastLatency:
+	default:
+		return fmt.Errorf("unknown replica policy %q", m.para.ReplicaPolicy)
+	}
+	if len(m.para.Replicas) == 0 {
+		return nil
+	}
+	if m.isSQLite() {
+		return fmt.Errorf("replicas are not supported by %s", DialectSQLite)
+	}
+	for _, conf := range m.para.Replicas {
+		db, err := m.open(m.hostURI(conf.Host, conf.Port))
+		if err != nil {
+			return fmt.Errorf("failed to connect MySQL replica %s, message: %v", conf.Host, err)
+		}
+		m.replicas = append(m.replicas, &replica{addr: net.JoinHostPort(conf.Host, conf.Port), db: db})
+	}
+	// the replicas are used once they answered a ping
+	m.checkReplicas()
+
+	interval := time.Duration(m.para.HealthCheckSeconds) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	m.stop = make(chan struct{})
+	go m.runHealthChecks(interval, m.stop)
+	return nil
+}
+
+func (m *MySQLDB) runHealthChecks(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.checkReplicas()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ping the replicas, evicting the ones which do not answer and bringing back the others.
+func (m *MySQLDB) checkReplicas() {
+	for _, r := range m.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), replicaPingTimeout)
+		started := time.Now()
+		err := r.db.PingContext(ctx)
+		elapsed := time.Since(started)
+		cancel()
+		if err != nil {
+			m.evict(r, err)
+			continue
+		}
+		m.mu.Lock()
+		if !r.healthy {
+			logrus.Info(fmt.Sprintf("MySQL replica %s is healthy", r.addr))
+			r.healthy = true
+			r.latency = elapsed
+		} else {
+			r.latency = (r.latency*7 + elapsed) / 8
+		}
+		m.mu.Unlock()
+	}
+}
+
+// stop using r until it answers a ping again.
+func (m *MySQLDB) evict(r *replica, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.healthy {
+		logrus.Warn(fmt.Sprintf("MySQL replica %s evicted, message: %v", r.addr, err))
+	}
+	r.healthy = false
+}
+
+// a healthy replica picked with the policy of the config, nil if there is none.
+func (m *MySQLDB) pickReplica() *replica {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var healthy []*replica
+	for _, r := range m.replicas {
+		if r.healthy {
+			healthy = append(healthy, r)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	if m.para.ReplicaPolicy == ReplicaLeastLatency {
+		best := healthy[0]
+		for _, r := range healthy[1:] {
+			if r.latency < best.latency {
+				best = r
+			}
+		}
+		return best
+	}
+	n := atomic.AddUint32(&m.next, 1)
+	return healthy[int(n-1)%len(healthy)]
+}
+
+// gorp map of r, sharing the tables of the primary.
+func (m *MySQLDB) replicaMap(r *replica) *gorp.DbMap {
+	dbmap := m.Db
+	dbmap.Db = r.db
+	return &dbmap
+}
+
+// Reader returns a healthy replica, or the primary if there is none.
+// NOTE the replication is asynchronous, use Db to read what was just written.
+func (m *MySQLDB) Reader() gorp.SqlExecutor {
+	if r := m.pickReplica(); r != nil {
+		return m.replicaMap(r)
+	}
+	return &m.Db
+}
+
+// run a read on a replica, or on the primary if there is none or the connection to it failed.
+func (m *MySQLDB) read(fn func(db gorp.SqlExecutor) error) error {
+	r := m.pickReplica()
+	if r == nil {
+		return fn(&m.Db)
+	}
+	err := fn(m.replicaMap(r))
+	if !isConnectionError(err) {
+		return err
+	}
+	m.evict(r, err)
+	return fn(&m.Db)
+}
+
+// errors showing that the server could not be reached, rather than a reply of it.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == driver.ErrBadConn || err == mysql.ErrInvalidConn || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// The writes go to the primary and the reads to the replicas.
+
+func (m *MySQLDB) Insert(list ...interface{}) error {
+	return m.Db.Insert(list...)
+}
+
+func (m *MySQLDB) Update(list ...interface{}) (int64, error) {
+	return m.Db.Update(list...)
+}
+
+func (m *MySQLDB) Delete(list ...interface{}) (int64, error) {
+	return m.Db.Delete(list...)
+}
+
+func (m *MySQLDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return m.Db.Exec(query, args...)
+}
+
+func (m *MySQLDB) Get(i interface{}, keys ...interface{}) (ret interface{}, err error) {
+	err = m.read(func(db gorp.SqlExecutor) error {
+		ret, err = db.Get(i, keys...)
+		return err
+	})
+	return ret, err
+}
+
+func (m *MySQLDB) Select(i interface{}, query string, args ...interface{}) (ret []interface{}, err error) {
+	err = m.read(func(db gorp.SqlExecutor) error {
+		ret, err = db.Select(i, query, args...)
+		return err
+	})
+	return ret, err
+}
+
+func (m *MySQLDB) SelectInt(query string, args ...interface{}) (ret int64, err error) {
+	err = m.read(func(db gorp.SqlExecutor) error {
+		ret, err = db.SelectInt(query, args...)
+		return err
+	})
+	return ret, err
+}
+
+func (m *MySQLDB) SelectNullInt(query string, args ...interface{}) (ret sql.NullInt64, err error) {
+	err = m.read(func(db gorp.SqlExecutor) error {
+		ret, err = db.SelectNullInt(query, args...)
+		return err
+	})
+	return ret, err
+}
+
+func (m *MySQLDB) SelectFloat(query string, args ...interface{}) (ret float64, err error) {
+	err = m.read(func(db gorp.SqlExecutor) error {
+		ret, err = db.SelectFloat(query, args...)
+		return err
+	})
+	return ret, err
+}
+
+func (m *MySQLDB) SelectNullFloat(query string, args ...interface{}) (ret sql.NullFloat64, err error) {
+	err = m.read(func(db gorp.SqlExecutor) error {
+		ret, err = db.SelectNullFloat(query, args...)
+		return err
+	})
+	return ret, err
+}
+
+func (m *MySQLDB) SelectStr(query string, args ...interface{}) (ret string, err error) {
+	err = m.read(func(db gorp.SqlExecutor) error {
+		ret, err = db.SelectStr(query, args...)
+		return err
+	})
+	return ret, err
+}
+
+func (m *MySQLDB) SelectNullStr(query string, args ...interface{}) (ret sql.NullString, err error) {
+	err = m.read(func(db gorp.SqlExecutor) error {
+		ret, err = db.SelectNullStr(query, args...)
+		return err
+	})
+	return ret, err
+}
+
+func (m *MySQLDB) SelectOne(holder interface{}, query string, args ...interface{}) error {
+	return m.read(func(db gorp.SqlExecutor) error {
+		return db.SelectOne(holder, query, args...)
+	})
+}
+
+// Query and QueryRow are read only as well, e.g. not for a SELECT ... FOR UPDATE.
+func (m *MySQLDB) Query(query string, args ...interface{}) (ret *sql.Rows, err error) {
+	err = m.read(func(db gorp.SqlExecutor) error {
+		ret, err = db.Query(query, args...)
+		return err
+	})
+	return ret, err
+}
+
+// The connection errors are only reported by Scan, so there is no failover to the primary.
+func (m *MySQLDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return m.Reader().QueryRow(query, args...)
+}
@@ -0,0 +1,72 @@
+package mysql
+
+import (
+	"database/sql"
+	"github.com/go-gorp/gorp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var _ gorp.SqlExecutor = &MySQLDB{}
+
+// sql.Open does not connect, so nothing needs to listen on the addresses
+func initReplicatedDB(t *testing.T, policy string) *MySQLDB {
+	conf := &MySQLConnInfo{
+		Uname:         "root",
+		Host:          "127.0.0.1",
+		Port:          "1",
+		Dbname:        "test",
+		MaxOpenConns:  4,
+		Replicas:      []MySQLReplica{{Host: "127.0.0.1", Port: "2"}, {Host: "127.0.0.1", Port: "3"}},
+		ReplicaPolicy: policy,
+	}
+	m, err := NewMySQLDb(conf)
+	assert.Nil(t, err)
+	return m
+}
+
+func TestMySQLDB_UnreachableReplicas(t *testing.T) {
+	m := initReplicatedDB(t, "")
+	defer m.Close()
+	assert.Equal(t, 4, m.Db.Db.Stats().MaxOpenConnections)
+	assert.Equal(t, 2, len(m.replicas))
+	for _, r := range m.replicas {
+		assert.False(t, r.healthy)
+	}
+	assert.Nil(t, m.pickReplica())
+	assert.Equal(t, &m.Db, m.Reader())
+}
+
+func TestMySQLDB_PickReplica(t *testing.T) {
+	m := initReplicatedDB(t, ReplicaRoundRobin)
+	defer m.Close()
+	a, b := m.replicas[0], m.replicas[1]
+	a.healthy, b.healthy = true, true
+
+	picked := map[*replica]int{}
+	for i := 0; i < 4; i++ {
+		picked[m.pickReplica()]++
+	}
+	assert.Equal(t, map[*replica]int{a: 2, b: 2}, picked)
+
+	// the reads share the tables of the primary
+	reader := m.Reader().(*gorp.DbMap)
+	assert.NotEqual(t, m.Db.Db, reader.Db)
+	assert.Equal(t, m.Db.Dialect, reader.Dialect)
+
+	m.evict(a, sql.ErrConnDone)
+	assert.Equal(t, b, m.pickReplica())
+	assert.Equal(t, b, m.pickReplica())
+
+	m.para.ReplicaPolicy = ReplicaLeastLatency
+	a.healthy = true
+	a.latency, b.latency = 5*time.Millisecond, time.Millisecond
+	assert.Equal(t, b, m.pickReplica())
+	assert.Equal(t, b, m.pickReplica())
+}
+
+func TestMySQLDB_UnknownReplicaPolicy(t *testing.T) {
+	_, err := NewMySQLDb(&MySQLConnInfo{Host: "127.0.0.1", Port: "1", ReplicaPolicy: "random"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,57 @@
+package mysql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+)
+
+// Values of MySQLConnInfo.Dialect, which are also the names of the database/sql drivers.
+//
+// The sqlite3 dialect runs the same gorp code against SQLite, e.g. an in-memory database in tests:
+//
+//	go test -tags "sqlite3 libsqlite3" ./...
+//
+//	db, err := mysql.NewMySQLDb(&mysql.MySQLConnInfo{Dialect: mysql.DialectSQLite, MigrationsDir: "migrations"})
+//
+// The driver is only imported with the sqlite3 build tag, which also makes ErrorKind tell its errors apart,
+// so that cgo is only needed by the programs using it.
+// NOTE the vendored go-sqlite3 has no amalgamation, the libsqlite3 tag links it against the system SQLite,
+// which needs its development package, e.g. libsqlite3-dev, on the build machine.
+// Dbname is the path of the database file, the database is in memory if it is empty or ":memory:".
+// NOTE the statements specific to MySQL, e.g. ON DUPLICATE KEY UPDATE, fail on SQLite.
+const (
+	DialectMySQL  = "mysql"
+	DialectSQLite = "sqlite3"
+)
+
+func (m *MySQLDB) isSQLite() bool {
+	return m.para.Dialect == DialectSQLite
+}
+
+func (m *MySQLDB) sqliteURI() string {
+	if m.para.Dbname == "" {
+		return ":memory:"
+	}
+	return m.para.Dbname
+}
+
+// An in-memory database belongs to its connection, so there is a single connection which is never closed.
+// It is also enough for a file since SQLite serializes the writes.
+// NOTE a query on Db while a transaction is open therefore waits for the end of the transaction.
+func initSQLite(db *sql.DB) error {
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	_, err := db.Exec("PRAGMA foreign_keys = ON")
+	return err
+}
+
+// the driver registered as name, e.g. by importing its package
+func registeredDriver(name string) (driver.Driver, error) {
+	db, err := sql.Open(name, "")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return db.Driver(), nil
+}
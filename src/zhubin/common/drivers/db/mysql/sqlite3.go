@@ -0,0 +1,25 @@
+//go:build sqlite3
+// +build sqlite3
+
+package mysql
+
+import (
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrorKind of the errors of go-sqlite3, by their codes.
+func sqliteErrorKind(err error) error {
+	e, ok := err.(sqlite3.Error)
+	if !ok {
+		return nil
+	}
+	switch {
+	case e.ExtendedCode == sqlite3.ErrConstraintUnique, e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+		return ErrDuplicateKey
+	case e.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+		return ErrForeignKeyViolation
+	case e.Code == sqlite3.ErrBusy, e.Code == sqlite3.ErrLocked:
+		return ErrLockWaitTimeout
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !sqlite3
+// +build !sqlite3
+
+package mysql
+
+// The errors of SQLite are only told apart when built with the sqlite3 tag.
+func sqliteErrorKind(err error) error {
+	return nil
+}
@@ -0,0 +1,143 @@
+//go:build sqlite3
+// +build sqlite3
+
+package mysql
+
+import (
+	"context"
+	"github.com/go-gorp/gorp"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type User struct {
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+var testMigrations = []*Migration{
+	SQLMigration(1, "create_users",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(64) NOT NULL UNIQUE);",
+		"DROP TABLE users;"),
+	SQLMigration(2, "create_roles",
+		"CREATE TABLE roles (user_id INTEGER NOT NULL REFERENCES users(id), role VARCHAR(64) NOT NULL);",
+		"DROP TABLE roles;"),
+}
+
+func initSQLiteDB(t *testing.T) *MySQLDB {
+	m, err := NewMySQLDb(&MySQLConnInfo{Dialect: DialectSQLite, SlowQueryMs: 1000})
+	assert.Nil(t, err)
+	m.Db.AddTableWithName(User{}, "users").SetKeys(true, "Id")
+	return m
+}
+
+func TestSQLite_Migrations(t *testing.T) {
+	m := initSQLiteDB(t)
+	defer m.Close()
+	migrator := NewMigrator(m, testMigrations...)
+
+	migrator.DryRun = true
+	applied, err := migrator.Up()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(applied))
+	status, err := migrator.Status()
+	assert.Nil(t, err)
+	assert.False(t, status[0].Applied)
+
+	migrator.DryRun = false
+	applied, err = migrator.UpTo(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(applied))
+	applied, err = migrator.Up()
+	assert.Nil(t, err)
+	assert.Equal(t, []*Migration{testMigrations[1]}, applied)
+	status, err = migrator.Status()
+	assert.Nil(t, err)
+	assert.True(t, status[0].Applied && status[1].Applied)
+	assert.False(t, status[1].AppliedAt.IsZero())
+
+	rolledBack, err := migrator.Down(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Migration{testMigrations[1]}, rolledBack)
+	_, err = m.Db.Exec("SELECT * FROM roles")
+	assert.NotNil(t, err)
+	status, err = migrator.Status()
+	assert.Nil(t, err)
+	assert.False(t, status[1].Applied)
+}
+
+func TestSQLite_FixturesAndTx(t *testing.T) {
+	m := initSQLiteDB(t)
+	defer m.Close()
+	_, err := NewMigrator(m, testMigrations...).Up()
+	assert.Nil(t, err)
+
+	dir, err := ioutil.TempDir("", "fixtures")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fixtures := `
+users:
+  - id: 1
+    name: alice
+  - id: 2
+    name: bob
+roles:
+  - user_id: 1
+    role: admin
+`
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "users.yml"), []byte(fixtures), 0644))
+	assert.Nil(t, m.LoadFixtures(dir))
+	// loading again replaces the rows
+	assert.Nil(t, m.LoadFixtures(dir))
+
+	u, err := m.Get(User{}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", u.(*User).Name)
+	n, err := m.SelectInt("SELECT COUNT(*) FROM roles WHERE user_id = ?", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// rolled back on error
+	err = m.WithTx(context.Background(), func(tx *gorp.Transaction) error {
+		if err := tx.Insert(&User{Name: "carol"}); err != nil {
+			return err
+		}
+		return tx.Insert(&User{Name: "alice"})
+	})
+	assert.Equal(t, ErrDuplicateKey, ErrorKind(err))
+	n, err = m.SelectInt("SELECT COUNT(*) FROM users")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	_, err = m.Exec("INSERT INTO roles (user_id, role) VALUES (?, ?)", 42, "admin")
+	assert.Equal(t, ErrForeignKeyViolation, ErrorKind(err))
+
+	err = m.WithTx(context.Background(), func(tx *gorp.Transaction) error {
+		return tx.Insert(&User{Name: "carol"})
+	})
+	assert.Nil(t, err)
+	n, err = m.SelectInt("SELECT COUNT(*) FROM users")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), n)
+}
+
+func TestSQLite_InvalidFixtures(t *testing.T) {
+	m := initSQLiteDB(t)
+	defer m.Close()
+	file, err := ioutil.TempFile("", "fixtures")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("users:\n  - id: 1\n    name: [a, b]\n")
+	file.Close()
+	assert.NotNil(t, m.LoadFixtures(file.Name()))
+}
+
+func TestSQLite_ErrorKind(t *testing.T) {
+	assert.Equal(t, ErrDuplicateKey, ErrorKind(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintPrimaryKey}))
+	assert.Equal(t, ErrLockWaitTimeout, ErrorKind(sqlite3.Error{Code: sqlite3.ErrBusy}))
+	assert.Nil(t, ErrorKind(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintNotNull}))
+}
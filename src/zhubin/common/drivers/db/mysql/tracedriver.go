@@ -0,0 +1,212 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"time"
+)
+
+// Opens the connections of a sql.DB with sql.OpenDB, wrapped to report their statements to the tracer.
+type tracedConnector struct {
+	dsn    string
+	driver driver.Driver
+	tracer *QueryTracer
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, tracer: c.tracer}, nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type tracedConn struct {
+	driver.Conn
+	tracer *QueryTracer
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query, tracer: c.tracer}, nil
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// keeps the conversions of the driver, driver.ErrSkip makes database/sql use the default ones
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// driver.ErrSkip makes database/sql prepare the statement, which is then traced by tracedStmt
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	started := time.Now()
+	var result driver.Result
+	var err error
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		result, err = e.ExecContext(ctx, query, args)
+	} else if e, ok := c.Conn.(driver.Execer); ok {
+		result, err = e.Exec(query, namedValues(args))
+	} else {
+		return nil, driver.ErrSkip
+	}
+	if err != driver.ErrSkip {
+		c.tracer.exec(query, args, started, result, err)
+	}
+	return result, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	started := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		rows, err = q.QueryContext(ctx, query, args)
+	} else if q, ok := c.Conn.(driver.Queryer); ok {
+		rows, err = q.Query(query, namedValues(args))
+	} else {
+		return nil, driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	if err != nil {
+		c.tracer.query(query, args, started, 0, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, query: query, args: args, started: started, tracer: c.tracer}, nil
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	query  string
+	tracer *QueryTracer
+}
+
+// keeps the conversions of the driver, e.g. of uint64 by mysql
+func (s *tracedStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if c, ok := s.Stmt.(driver.ColumnConverter); ok {
+		return c.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+func (s *tracedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesNamed(args))
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	started := time.Now()
+	var result driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(namedValues(args))
+	}
+	s.tracer.exec(s.query, args, started, result, err)
+	return result, err
+}
+
+func (s *tracedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesNamed(args))
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	started := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedValues(args))
+	}
+	if err != nil {
+		s.tracer.query(s.query, args, started, 0, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, query: s.query, args: args, started: started, tracer: s.tracer}, nil
+}
+
+// A query is reported when its rows are closed, with the number of rows read.
+type tracedRows struct {
+	driver.Rows
+	query   string
+	args    []driver.NamedValue
+	started time.Time
+	tracer  *QueryTracer
+	count   int64
+	err     error
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == nil {
+		r.count++
+	} else if err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	r.tracer.query(r.query, r.args, r.started, r.count, r.err)
+	return err
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+func valuesNamed(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
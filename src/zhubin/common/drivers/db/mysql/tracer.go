@@ -0,0 +1,331 @@
+package mysql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Columns whose values are never logged, matched case insensitively as a substring of the name of the column.
+var DefaultRedactColumns = []string{"password", "passwd", "secret", "token", "credential"}
+
+// the logs of a query in the current second
+type sampleWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// the number of queries sampled before the windows are dropped, e.g. with queries built without placeholders
+const maxSampledQueries = 10000
+
+// Logs the statements run by MySQLDB with structured fields: duration, rows affected or read,
+// table, caller and args. The values of the sensitive columns are redacted.
+type QueryTracer struct {
+	// Log every statement at Debug level, otherwise only the slow and the failed ones.
+	Verbose bool
+	// Statements slower than this are logged at Warn level with their EXPLAIN, 0 disables.
+	SlowThreshold time.Duration
+	// Logs of a same statement per second at most, the slow and failed ones included, 0 means no limit.
+	// The number of logs suppressed is reported by the next one.
+	SamplePerSecond int
+	// Added to DefaultRedactColumns.
+	RedactColumns []string
+
+	// runs the EXPLAIN of the slow SELECT statements
+	db          *sql.DB
+	explainStmt string
+
+	mu      sync.Mutex
+	samples map[string]*sampleWindow
+}
+
+func newQueryTracer(conf *MySQLConnInfo) *QueryTracer {
+	t := &QueryTracer{
+		Verbose:         conf.TraceQueries,
+		SlowThreshold:   time.Duration(conf.SlowQueryMs) * time.Millisecond,
+		SamplePerSecond: conf.TraceSamplePerSecond,
+		RedactColumns:   conf.RedactColumns,
+		samples:         make(map[string]*sampleWindow),
+	}
+	t.explainStmt = explainPrefix
+	if conf.Dialect == DialectSQLite {
+		t.explainStmt = explainPrefix + "QUERY PLAN "
+	}
+	return t
+}
+
+func (t *QueryTracer) exec(query string, args []driver.NamedValue, started time.Time, result driver.Result, err error) {
+	var rows int64
+	if result != nil && err == nil {
+		rows, _ = result.RowsAffected()
+	}
+	t.trace(query, args, started, "rows_affected", rows, err)
+}
+
+func (t *QueryTracer) query(query string, args []driver.NamedValue, started time.Time, rows int64, err error) {
+	t.trace(query, args, started, "rows", rows, err)
+}
+
+func (t *QueryTracer) trace(query string, args []driver.NamedValue, started time.Time, rowsField string, rows int64, err error) {
+	elapsed := time.Since(started)
+	slow := t.SlowThreshold > 0 && elapsed >= t.SlowThreshold
+	if !t.Verbose && !slow && err == nil {
+		return
+	}
+	if t.Verbose && !slow && err == nil && logrus.GetLevel() < logrus.DebugLevel {
+		return
+	}
+	// the EXPLAIN run by the tracer itself
+	if strings.HasPrefix(query, explainPrefix) {
+		return
+	}
+	suppressed, ok := t.sample(query)
+	if !ok {
+		return
+	}
+	fields := logrus.Fields{
+		"query":    query,
+		"duration": elapsed.String(),
+		rowsField:  rows,
+		"table":    tableOf(query),
+		"caller":   caller(),
+	}
+	if len(args) > 0 {
+		fields["args"] = t.redact(query, args)
+	}
+	if suppressed > 0 {
+		fields["suppressed"] = suppressed
+	}
+	entry := logrus.WithFields(fields)
+	switch {
+	case err != nil:
+		entry.WithField("error", err.Error()).Warn("mysql query failed")
+	case slow:
+		if t.db != nil && isSelect(query) {
+			values := namedValues(args)
+			go func() {
+				plan, err := t.explain(query, values)
+				if err != nil {
+					plan = "EXPLAIN failed: " + err.Error()
+				}
+				entry.WithField("explain", plan).Warn("mysql slow query")
+			}()
+		} else {
+			entry.Warn("mysql slow query")
+		}
+	default:
+		entry.Debug("mysql query")
+	}
+}
+
+// whether to log query, and the number of logs of it suppressed since the last one
+func (t *QueryTracer) sample(query string) (int, bool) {
+	if t.SamplePerSecond <= 0 {
+		return 0, true
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.samples[query]
+	if !ok {
+		if len(t.samples) >= maxSampledQueries {
+			t.samples = make(map[string]*sampleWindow)
+		}
+		w = &sampleWindow{start: now}
+		t.samples[query] = w
+	}
+	if now.Sub(w.start) >= time.Second {
+		w.start, w.count = now, 0
+	}
+	if w.count >= t.SamplePerSecond {
+		w.suppressed++
+		return 0, false
+	}
+	w.count++
+	suppressed := w.suppressed
+	w.suppressed = 0
+	return suppressed, true
+}
+
+// the args formatted as gorp does, the values of the sensitive columns replaced with ***
+func (t *QueryTracer) redact(query string, args []driver.NamedValue) string {
+	columns := placeholderColumns(query, len(args))
+	var b strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		if t.sensitive(columns[i]) {
+			fmt.Fprintf(&b, "%d:***", i+1)
+		} else {
+			fmt.Fprintf(&b, "%d:%#v", i+1, arg.Value)
+		}
+	}
+	return b.String()
+}
+
+func (t *QueryTracer) sensitive(column string) bool {
+	if column == "" {
+		return false
+	}
+	column = strings.ToLower(column)
+	for _, lists := range [][]string{DefaultRedactColumns, t.RedactColumns} {
+		for _, c := range lists {
+			if c != "" && strings.Contains(column, strings.ToLower(c)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+const explainPrefix = "EXPLAIN "
+
+// the plan of query, a line per row of the EXPLAIN output
+func (t *QueryTracer) explain(query string, args []driver.Value) (string, error) {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	rows, err := t.db.Query(t.explainStmt+query, values...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for rows.Next() {
+		cells := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range cells {
+			dest[i] = &cells[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		parts := make([]string, 0, len(columns))
+		for i, c := range columns {
+			if cells[i].Valid {
+				parts = append(parts, c+"="+cells[i].String)
+			}
+		}
+		lines = append(lines, strings.Join(parts, " "))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+var (
+	selectQuery  = regexp.MustCompile(`(?i)^\s*(\(\s*)?select\s`)
+	tableQuery   = regexp.MustCompile("(?i)\\b(?:from|into|update|join|table)\\s+(`[^`]+`|[\\w.]+)")
+	insertQuery  = regexp.MustCompile("(?is)^\\s*(?:insert|replace)\\s+(?:ignore\\s+)?(?:into\\s+)?\\S+\\s*\\(([^)]*)\\)\\s*values")
+	columnBefore = regexp.MustCompile("(`[^`]+`|[\\w.]+)\\s*(?:=|<>|!=|<=>|<=|>=|<|>|\\s(?i:like))\\s*$")
+)
+
+func isSelect(query string) bool {
+	return selectQuery.MatchString(query)
+}
+
+// first table of query, empty if not found
+func tableOf(query string) string {
+	match := tableQuery.FindStringSubmatch(query)
+	if match == nil {
+		return ""
+	}
+	return strings.Trim(match[1], "`")
+}
+
+// the column of each of the n placeholders of query, empty if not found:
+// the column at the same position in the column list of an INSERT, or the column compared with it, e.g. password = ?
+func placeholderColumns(query string, n int) []string {
+	columns := make([]string, n)
+	// the VALUES of an INSERT start at values, the placeholders of its tuples are told apart
+	// by their position, since some of the values may be literals, e.g. null for an auto-increment key
+	var names []string
+	values := len(query)
+	if match := insertQuery.FindStringSubmatchIndex(query); match != nil {
+		names = strings.Split(query[match[2]:match[3]], ",")
+		values = match[1]
+	}
+	i, depth, pos := 0, 0, 0
+	var quote byte
+	for p := 0; p < len(query) && i < n; p++ {
+		c := query[p]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				p++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			if depth > 0 {
+				if pos < len(names) {
+					columns[i] = strings.Trim(strings.TrimSpace(names[pos]), "`")
+				}
+			} else {
+				columns[i] = columnComparedTo(query[:p])
+			}
+			i++
+		case p < values:
+		case c == '(':
+			depth++
+			if depth == 1 {
+				pos = 0
+			}
+		case c == ')':
+			depth--
+		case c == ',' && depth == 1:
+			pos++
+		}
+	}
+	return columns
+}
+
+// the column compared with the placeholder following query, empty if not found
+func columnComparedTo(query string) string {
+	match := columnBefore.FindStringSubmatch(query)
+	if match == nil {
+		return ""
+	}
+	name := strings.Trim(match[1], "`")
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+		name = name[dot+1:]
+	}
+	return name
+}
+
+// the name of this package, to skip its frames
+var tracerPackage = reflect.TypeOf(tracedConnector{}).PkgPath() + "."
+
+// the first function outside of database/sql, gorp and this driver, e.g. a method of a repository
+func caller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		fn := frame.Function
+		internal := strings.HasPrefix(fn, "database/sql.") || strings.HasPrefix(fn, "runtime.") ||
+			strings.Contains(fn, "github.com/go-gorp/gorp.") ||
+			(strings.HasPrefix(fn, tracerPackage) && !strings.HasSuffix(frame.File, "_test.go"))
+		if !internal {
+			return fmt.Sprintf("%s:%d", fn, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/go-gorp/gorp"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+// driver whose statements affect 2 rows and return 3 rows
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type fakeStmt struct{}
+
+func (*fakeStmt) Close() error  { return nil }
+func (*fakeStmt) NumInput() int { return -1 }
+func (*fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return fakeResult{}, nil
+}
+
+// 2 rows affected, the last one inserted with id 1
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 1, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 2, nil }
+func (*fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	n int
+}
+
+func (*fakeRows) Columns() []string { return []string{"id"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 3 {
+		return io.EOF
+	}
+	r.n++
+	dest[0] = int64(r.n)
+	return nil
+}
+
+func TestQueryTracer(t *testing.T) {
+	hook := test.NewGlobal()
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	defer logrus.SetLevel(level)
+
+	tracer := newQueryTracer(&MySQLConnInfo{TraceQueries: true, TraceSamplePerSecond: 2, RedactColumns: []string{"ssn"}})
+	db := sql.OpenDB(&tracedConnector{driver: fakeDriver{}, tracer: tracer})
+	defer db.Close()
+
+	_, err := db.Exec("INSERT INTO `users` (`name`,`password`,`ssn`) VALUES (?,?,?)", "alice", "s3cret", "123")
+	assert.Nil(t, err)
+	entry := hook.LastEntry()
+	assert.Equal(t, logrus.DebugLevel, entry.Level)
+	assert.Equal(t, "users", entry.Data["table"])
+	assert.Equal(t, int64(2), entry.Data["rows_affected"])
+	assert.Equal(t, `1:"alice" 2:*** 3:***`, entry.Data["args"])
+	assert.Contains(t, entry.Data["caller"], "TestQueryTracer")
+
+	hook.Reset()
+	for i := 0; i < 5; i++ {
+		rows, err := db.Query("SELECT id FROM users u WHERE u.name = ? AND u.password = ?", "alice", "s3cret")
+		assert.Nil(t, err)
+		for rows.Next() {
+		}
+		rows.Close()
+	}
+	entries := hook.AllEntries()
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, int64(3), entries[0].Data["rows"])
+	assert.Equal(t, `1:"alice" 2:***`, entries[0].Data["args"])
+
+	// the logs suppressed are reported by the next one
+	time.Sleep(time.Second)
+	hook.Reset()
+	rows, err := db.Query("SELECT id FROM users u WHERE u.name = ? AND u.password = ?", "alice", "s3cret")
+	assert.Nil(t, err)
+	rows.Close()
+	assert.Equal(t, 3, hook.LastEntry().Data["suppressed"])
+}
+
+// accepts any value, e.g. uint64 >= 1<<63, and counts the resets of the session
+type checkingDriver struct {
+	resets *int
+}
+
+func (d checkingDriver) Open(name string) (driver.Conn, error) { return checkingConn{d.resets}, nil }
+
+type checkingConn struct {
+	resets *int
+}
+
+func (checkingConn) Prepare(query string) (driver.Stmt, error)   { return &fakeStmt{}, nil }
+func (checkingConn) Close() error                                { return nil }
+func (checkingConn) Begin() (driver.Tx, error)                   { return nil, driver.ErrSkip }
+func (checkingConn) CheckNamedValue(nv *driver.NamedValue) error { return nil }
+func (c checkingConn) ResetSession(ctx context.Context) error {
+	*c.resets++
+	return nil
+}
+
+func TestQueryTracer_Gorp(t *testing.T) {
+	hook := test.NewGlobal()
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	defer logrus.SetLevel(level)
+
+	tracer := newQueryTracer(&MySQLConnInfo{TraceQueries: true})
+	db := sql.OpenDB(&tracedConnector{driver: fakeDriver{}, tracer: tracer})
+	defer db.Close()
+	type account struct {
+		Id       int64  `db:"id"`
+		Name     string `db:"name"`
+		Password string `db:"password"`
+	}
+	dbmap := gorp.DbMap{Db: db, Dialect: gorp.MySQLDialect{"InnoDB", "utf8"}}
+	dbmap.AddTableWithName(account{}, "accounts").SetKeys(true, "Id")
+
+	// insert into `accounts` (`id`,`name`,`password`) values (null,?,?);
+	a := &account{Name: "alice", Password: "s3cret"}
+	assert.Nil(t, dbmap.Insert(a))
+	assert.Equal(t, int64(1), a.Id)
+	assert.Equal(t, `1:"alice" 2:***`, hook.LastEntry().Data["args"])
+}
+
+func TestTracedConn_Forward(t *testing.T) {
+	resets := 0
+	tracer := newQueryTracer(&MySQLConnInfo{TraceQueries: true})
+	db := sql.OpenDB(&tracedConnector{driver: checkingDriver{&resets}, tracer: tracer})
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err := db.Exec("UPDATE t SET n = ?", uint64(1<<63))
+	assert.Nil(t, err)
+	_, err = db.Exec("UPDATE t SET n = ?", uint64(1<<63))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, resets)
+}
+
+func TestQueryTracer_Slow(t *testing.T) {
+	hook := test.NewGlobal()
+	tracer := newQueryTracer(&MySQLConnInfo{SlowQueryMs: 1})
+	db := sql.OpenDB(&tracedConnector{driver: fakeDriver{}, tracer: tracer})
+	defer db.Close()
+
+	_, err := db.Exec("UPDATE users SET name = ? WHERE id = ?", "bob", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(hook.AllEntries()))
+
+	tracer.trace("UPDATE users SET name = ? WHERE id = ?", nil, time.Now().Add(-time.Second), "rows_affected", 1, nil)
+	entry := hook.LastEntry()
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, "mysql slow query", entry.Message)
+}
+
+func TestPlaceholderColumns(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "a", "b"}, placeholderColumns("insert into t (`a`, b) values (?,?),(?,?)", 4))
+	assert.Equal(t, []string{"passwd", "id", ""}, placeholderColumns("UPDATE t SET t.`passwd`=? WHERE id >= ? AND name IN (?) AND x = '?'", 3))
+	assert.Equal(t, []string{"name"}, placeholderColumns("SELECT * FROM t WHERE name LIKE ?", 1))
+	assert.Equal(t, []string{"name", "password"}, placeholderColumns("insert into `users` (`id`,`name`,`password`) values (null,?,?);", 2))
+	assert.Equal(t, []string{"a", "c", "b"}, placeholderColumns("INSERT INTO t (a, `b`, c) VALUES (?, NOW(), LOWER(?)) ON DUPLICATE KEY UPDATE b = ?", 3))
+	assert.Equal(t, []string{"b", "b"}, placeholderColumns("INSERT INTO t (a, b) VALUES ('?', ?), (1, ?)", 2))
+
+	assert.Equal(t, "users", tableOf("select * from `users` join roles"))
+	assert.Equal(t, "db.users", tableOf("DELETE FROM db.users WHERE id = ?"))
+	assert.True(t, isSelect(" (SELECT 1)"))
+	assert.False(t, isSelect("UPDATE t SET a = (SELECT 1)"))
+}
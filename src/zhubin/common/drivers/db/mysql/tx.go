@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-gorp/gorp"
+	"github.com/sirupsen/logrus"
+	"math/rand"
+	"time"
+)
+
+// WithTx runs fn in a transaction of the primary, committed if fn returns nil and rolled back otherwise,
+// also when fn panics. fn is run again in a new transaction, after an exponential backoff, when
+// the transaction fails with a deadlock or a lock wait timeout, so it must not have other side effects.
+// The errors are classified, see Classify. ctx cancels the retries, gorp does not pass it to the queries.
+//
+//	err := db.WithTx(ctx, func(tx *gorp.Transaction) error {
+//		if _, err := tx.Exec("UPDATE accounts SET balance = balance - ? WHERE id = ?", amount, from); err != nil {
+//			return err
+//		}
+//		_, err := tx.Exec("UPDATE accounts SET balance = balance + ? WHERE id = ?", amount, to)
+//		return err
+//	})
+func (m *MySQLDB) WithTx(ctx context.Context, fn func(tx *gorp.Transaction) error) error {
+	delay := m.TxRetryDelay
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := m.runTx(fn)
+		kind := ErrorKind(err)
+		if (kind != ErrDeadlock && kind != ErrLockWaitTimeout) || attempt >= m.TxRetries {
+			return Classify(err)
+		}
+		// with jitter, so that the transactions in conflict do not retry in lockstep
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay)+1))
+		logrus.Warn(fmt.Sprintf("MySQL transaction retry %d in %v, message: %v", attempt+1, wait, err))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+func (m *MySQLDB) runTx(fn func(tx *gorp.Transaction) error) error {
+	tx, err := m.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,46 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"github.com/go-gorp/gorp"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	dup := &mysql.MySQLError{Number: ER_DUP_ENTRY, Message: "Duplicate entry 'a' for key 'name'"}
+	err := Classify(dup)
+	assert.Equal(t, ErrDuplicateKey, ErrorKind(err))
+	assert.True(t, errors.Is(err, ErrDuplicateKey))
+	assert.Contains(t, err.Error(), "Duplicate entry 'a'")
+	assert.Equal(t, err, Classify(err))
+
+	assert.Equal(t, ErrForeignKeyViolation, ErrorKind(&mysql.MySQLError{Number: ER_NO_REFERENCED_ROW_2}))
+	assert.Equal(t, ErrDeadlock, ErrorKind(&mysql.MySQLError{Number: ER_LOCK_DEADLOCK}))
+	assert.Equal(t, ErrLockWaitTimeout, ErrorKind(&mysql.MySQLError{Number: ER_LOCK_WAIT_TIMEOUT}))
+	assert.Equal(t, ErrConnectionLost, ErrorKind(mysql.ErrInvalidConn))
+
+	other := &mysql.MySQLError{Number: ER_NO_SUCH_TABLE}
+	assert.Nil(t, ErrorKind(other))
+	assert.Equal(t, other, Classify(other))
+	assert.Nil(t, Classify(nil))
+}
+
+func TestMySQLDB_WithTxUnreachable(t *testing.T) {
+	m, err := NewMySQLDb(&MySQLConnInfo{Uname: "root", Host: "127.0.0.1", Port: "1", Dbname: "test"})
+	assert.Nil(t, err)
+	defer m.Close()
+	called := false
+	err = m.WithTx(context.Background(), func(tx *gorp.Transaction) error {
+		called = true
+		return nil
+	})
+	assert.Equal(t, ErrConnectionLost, ErrorKind(err))
+	assert.False(t, called)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, m.WithTx(ctx, func(tx *gorp.Transaction) error { return nil }))
+}
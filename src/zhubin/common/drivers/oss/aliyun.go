@@ -2,19 +2,26 @@ package oss
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
 	"fmt"
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"io"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type AliYun struct {
 	client *oss.Bucket
 }
 
-var ErrMsgAliyunObjectNotFound = "oss: service returned error: StatusCode=404, ErrorCode=NoSuchKey, ErrorMessage=The specified key does not exist."
-
 func NewAliYunClient(host, port, accessKeyId, SecretAccressKey, bucket string) *AliYun {
 	if !validate(host, port, accessKeyId, SecretAccressKey, bucket) {
 		panic("Uninitialized oss driver.")
@@ -43,56 +50,68 @@ func NewAliYunClient(host, port, accessKeyId, SecretAccressKey, bucket string) *
 	return &AliYun{client: bkt}
 }
 
-func (ali *AliYun) PutObject(tenant string, objKey string, object io.Reader, overwrite bool) error {
+func (ali *AliYun) PutObject(tenant string, objKey string, object io.Reader, overwrite bool, options ...PutOptions) error {
 	if !overwrite {
-		exist, err := ali.CheckExist(tenant, objKey)
-		if err != nil {
-			return err
-		}
-		if exist {
-			return nil
-		}
+		_, err := ali.ConditionalPutObject(tenant, objKey, object, PutCondition{IfNotExist: true}, options...)
+		return err
 	}
-	return ali.client.PutObject(tenant+"/"+objKey, object)
+	return aliyunError(ali.client.PutObject(tenant+"/"+objKey, object, aliyunOptions(putOptions(objKey, options))...))
 }
 
-func (ali *AliYun) FPutObject(tenant string, objKey string, filePath string, overwrite bool) error {
+func (ali *AliYun) FPutObject(tenant string, objKey string, filePath string, overwrite bool, options ...PutOptions) error {
 	if !overwrite {
-		exist, err := ali.CheckExist(tenant, objKey)
+		file, err := os.Open(filePath)
 		if err != nil {
 			return err
 		}
-		if exist {
-			return nil
-		}
+		defer file.Close()
+		_, err = ali.ConditionalPutObject(tenant, objKey, file, PutCondition{IfNotExist: true}, options...)
+		return err
 	}
-	return ali.client.PutObjectFromFile(tenant+"/"+objKey, filePath)
+	return aliyunError(ali.client.PutObjectFromFile(tenant+"/"+objKey, filePath, aliyunOptions(putOptions(objKey, options))...))
 }
 
-func (ali *AliYun) BPutObject(tenant string, objKey string, objData []byte, overwrite bool) error {
-	if !overwrite {
-		exist, err := ali.CheckExist(tenant, objKey)
-		if err != nil {
-			return err
-		}
-		if exist {
-			return nil
-		}
-	}
-	return ali.client.PutObject(tenant+"/"+objKey, bytes.NewReader(objData))
+func (ali *AliYun) BPutObject(tenant string, objKey string, objData []byte, overwrite bool, options ...PutOptions) error {
+	return ali.PutObject(tenant, objKey, bytes.NewReader(objData), overwrite, options...)
 }
 
 func (ali *AliYun) GetObject(tenant string, objKey string) (io.ReadCloser, error) {
 	obj, err := ali.client.GetObject(tenant + "/" + objKey)
-	if err != nil && strings.Contains(err.Error(), ErrMsgAliyunObjectNotFound) {
-		return nil, ErrNotFound
+	if err != nil {
+		return nil, aliyunError(err)
 	}
-	return obj, err
+	return obj, nil
+}
+
+// Header of GetObject which makes OSS truncate a range past the end of the object and reject one starting past it,
+// instead of ignoring the range and returning the whole object.
+const aliyunRangeBehavior = "X-Oss-Range-Behavior"
+
+// The vendored sdk has no option for the range behavior header,
+// so the request is sent through its connection which signs any header given.
+func (ali *AliYun) GetObjectRange(tenant string, objKey string, offset int64, length int64) (io.ReadCloser, error) {
+	if offset == 0 && length <= 0 {
+		return ali.GetObject(tenant, objKey)
+	}
+	objRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		objRange += strconv.FormatInt(offset+length-1, 10)
+	}
+	headers := map[string]string{oss.HTTPHeaderRange: objRange, aliyunRangeBehavior: "standard"}
+	resp, err := ali.client.Client.Conn.Do(http.MethodGet, ali.client.BucketName, tenant+"/"+objKey, "", "", headers, nil, 0, nil)
+	if err != nil {
+		return nil, aliyunError(err)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, ErrInvalidRange
+	}
+	return resp.Body, nil
 }
 
 func (ali *AliYun) RemoveObject(tenant string, objKey string) error {
-	err := ali.client.DeleteObject(tenant + "/" + objKey)
-	if err != nil && strings.Contains(err.Error(), ErrMsgAliyunObjectNotFound) {
+	err := aliyunError(ali.client.DeleteObject(tenant + "/" + objKey))
+	if err == ErrNotFound {
 		return nil
 	}
 	return err
@@ -101,14 +120,267 @@ func (ali *AliYun) RemoveObject(tenant string, objKey string) error {
 func (ali *AliYun) ReadObject(tenant string, objKey string) ([]byte, error) {
 	obj, err := ali.client.GetObject(tenant + "/" + objKey)
 	if err != nil {
-		if strings.Contains(err.Error(), ErrMsgAliyunObjectNotFound) {
-			return nil, ErrNotFound
+		return nil, aliyunError(err)
+	}
+	defer obj.Close()
+	data, err := ioutil.ReadAll(obj)
+	if err != nil {
+		return nil, aliyunError(err)
+	}
+	return data, nil
+}
+
+func (ali *AliYun) CheckExist(tenant string, objKey string) (bool, error) {
+	exist, err := ali.client.IsObjectExist(tenant + "/" + objKey)
+	return exist, aliyunError(err)
+}
+
+func (ali *AliYun) ListObjects(tenant string, prefix string, marker string, limit int) (*ListObjectsResult, error) {
+	m, err := decodeMarker(marker)
+	if err != nil {
+		return nil, err
+	}
+	tenantPrefix := tenant + "/"
+	if m.Key != "" {
+		marker = tenantPrefix + m.Key
+	}
+	ret, err := ali.client.ListObjects(oss.Prefix(tenantPrefix+prefix), oss.Marker(marker), oss.MaxKeys(listLimit(limit)))
+	if err != nil {
+		return nil, aliyunError(err)
+	}
+	result := &ListObjectsResult{Objects: make([]ObjectInfo, 0, len(ret.Objects))}
+	for _, obj := range ret.Objects {
+		result.Objects = append(result.Objects, ObjectInfo{
+			Key:          strings.TrimPrefix(obj.Key, tenantPrefix),
+			Size:         obj.Size,
+			ETag:         trimETag(obj.ETag),
+			LastModified: obj.LastModified,
+		})
+	}
+	if ret.IsTruncated && len(result.Objects) > 0 {
+		result.NextMarker = encodeMarker(result.Objects[len(result.Objects)-1].Key, "")
+	}
+	return result, nil
+}
+
+func (ali *AliYun) StatObject(tenant string, objKey string) (*ObjectStat, error) {
+	header, err := ali.client.GetObjectDetailedMeta(tenant + "/" + objKey)
+	if err != nil {
+		// the response to HEAD has no body, so the sdk does not tell a missing object from other errors
+		if _, ok := err.(oss.ServiceError); !ok {
+			if exist, e := ali.client.IsObjectExist(tenant + "/" + objKey); e == nil && !exist {
+				return nil, ErrNotFound
+			}
 		}
+		return nil, aliyunError(err)
+	}
+	size, err := strconv.ParseInt(header.Get(oss.HTTPHeaderContentLength), 10, 64)
+	if err != nil {
 		return nil, err
 	}
-	return ioutil.ReadAll(obj)
+	lastModified, err := http.ParseTime(header.Get(oss.HTTPHeaderLastModified))
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectStat{
+		ObjectInfo: ObjectInfo{
+			Key:          objKey,
+			Size:         size,
+			ETag:         trimETag(header.Get(oss.HTTPHeaderEtag)),
+			LastModified: lastModified,
+		},
+		ContentType:        header.Get(oss.HTTPHeaderContentType),
+		ContentDisposition: header.Get(oss.HTTPHeaderContentDisposition),
+		CacheControl:       header.Get(oss.HTTPHeaderCacheControl),
+		Metadata:           metadataFromHeader(header, oss.HTTPHeaderOssMetaPrefix),
+	}, nil
 }
 
-func (ali *AliYun) CheckExist(tenant string, objKey string) (bool, error) {
-	return ali.client.IsObjectExist(tenant + "/" + objKey)
+func aliyunOptions(opts PutOptions) []oss.Option {
+	options := []oss.Option{oss.ContentType(opts.ContentType)}
+	if opts.ContentDisposition != "" {
+		options = append(options, oss.ContentDisposition(opts.ContentDisposition))
+	}
+	if opts.CacheControl != "" {
+		options = append(options, oss.CacheControl(opts.CacheControl))
+	}
+	for k, v := range opts.Metadata {
+		options = append(options, oss.Meta(k, v))
+	}
+	return options
+}
+
+// Header of PutObject which makes it fail with FileAlreadyExists instead of overwriting.
+const aliyunForbidOverwrite = "X-Oss-Forbid-Overwrite"
+
+// The vendored sdk has no option for the forbid overwrite header,
+// so the request is sent through its connection which signs any header given.
+// NOTE OSS can not overwrite conditionally, IfMatch is not supported.
+func (ali *AliYun) ConditionalPutObject(tenant string, objKey string, object io.Reader, cond PutCondition, options ...PutOptions) (*PutResult, error) {
+	if cond.IfMatch != "" && !cond.IfNotExist {
+		return nil, ErrNotSupported
+	}
+	headers := aliyunHeaders(putOptions(objKey, options))
+	if cond.IfNotExist {
+		headers[aliyunForbidOverwrite] = "true"
+	}
+	resp, err := ali.client.Client.Conn.Do(http.MethodPut, ali.client.BucketName, tenant+"/"+objKey, "", "", headers, object, 0, nil)
+	var etag string
+	if resp != nil {
+		resp.Body.Close()
+		etag = resp.Headers.Get(oss.HTTPHeaderEtag)
+	}
+	return conditionalResult(cond, etag, aliyunError(err))
+}
+
+func aliyunHeaders(opts PutOptions) map[string]string {
+	headers := map[string]string{oss.HTTPHeaderContentType: opts.ContentType}
+	if opts.ContentDisposition != "" {
+		headers[oss.HTTPHeaderContentDisposition] = opts.ContentDisposition
+	}
+	if opts.CacheControl != "" {
+		headers[oss.HTTPHeaderCacheControl] = opts.CacheControl
+	}
+	for k, v := range opts.Metadata {
+		headers[oss.HTTPHeaderOssMetaPrefix+k] = v
+	}
+	return headers
+}
+
+// The vendored sdk can not sign urls, so build it the same way as the sdk signs headers:
+// https://help.aliyun.com/document_detail/31952.html
+func (ali *AliYun) PresignedURL(tenant string, objKey string, method string, expires time.Duration) (string, error) {
+	var contentType string
+	switch method {
+	case http.MethodGet:
+	case http.MethodPut:
+		contentType = putOptions(objKey, nil).ContentType
+	default:
+		return "", ErrInvalidMethod
+	}
+	config := ali.client.Client.Config
+	objectName := tenant + "/" + objKey
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+
+	resource := "/" + ali.client.BucketName + "/" + objectName
+	params := url.Values{}
+	if config.SecurityToken != "" {
+		resource += "?security-token=" + config.SecurityToken
+		params.Set("security-token", config.SecurityToken)
+	}
+	signStr := method + "\n\n" + contentType + "\n" + expiresAt + "\n" + resource
+	h := hmac.New(sha1.New, []byte(config.AccessKeySecret))
+	h.Write([]byte(signStr))
+
+	params.Set("OSSAccessKeyId", config.AccessKeyID)
+	params.Set("Expires", expiresAt)
+	params.Set("Signature", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	return ali.objectURL(objectName) + "?" + params.Encode(), nil
+}
+
+func (ali *AliYun) objectURL(objectName string) string {
+	scheme, netLoc := "http", ali.client.Client.Config.Endpoint
+	if strings.HasPrefix(netLoc, "https://") {
+		scheme, netLoc = "https", strings.TrimPrefix(netLoc, "https://")
+	} else {
+		netLoc = strings.TrimPrefix(netLoc, "http://")
+	}
+	segments := strings.Split(objectName, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	escaped := strings.Join(segments, "/")
+
+	host, _, err := net.SplitHostPort(netLoc)
+	if err != nil {
+		host = netLoc
+	}
+	// Endpoints given as ip only support path style urls.
+	if net.ParseIP(host) != nil {
+		return fmt.Sprintf("%s://%s/%s/%s", scheme, netLoc, ali.client.BucketName, escaped)
+	}
+	return fmt.Sprintf("%s://%s.%s/%s", scheme, ali.client.BucketName, netLoc, escaped)
+}
+
+func (ali *AliYun) InitiateMultipartUpload(tenant string, objKey string, options ...PutOptions) (string, error) {
+	imur, err := ali.client.InitiateMultipartUpload(tenant+"/"+objKey, aliyunOptions(putOptions(objKey, options))...)
+	if err != nil {
+		return "", aliyunError(err)
+	}
+	return imur.UploadID, nil
+}
+
+func (ali *AliYun) UploadPart(tenant string, objKey string, uploadID string, partNumber int, part io.Reader, size int64) (*PartInfo, error) {
+	uploadPart, err := ali.client.UploadPart(ali.imur(tenant, objKey, uploadID), part, size, partNumber)
+	if err != nil {
+		return nil, aliyunError(err)
+	}
+	return &PartInfo{PartNumber: uploadPart.PartNumber, ETag: trimETag(uploadPart.ETag), Size: size}, nil
+}
+
+func (ali *AliYun) ListParts(tenant string, objKey string, uploadID string) ([]PartInfo, error) {
+	ret, err := ali.client.ListUploadedParts(ali.imur(tenant, objKey, uploadID))
+	if err != nil {
+		return nil, aliyunError(err)
+	}
+	parts := make([]PartInfo, 0, len(ret.UploadedParts))
+	for _, part := range ret.UploadedParts {
+		parts = append(parts, PartInfo{PartNumber: part.PartNumber, ETag: trimETag(part.ETag), Size: int64(part.Size)})
+	}
+	sortParts(parts)
+	return parts, nil
+}
+
+func (ali *AliYun) CompleteMultipartUpload(tenant string, objKey string, uploadID string, parts []PartInfo) error {
+	uploadParts := make([]oss.UploadPart, 0, len(parts))
+	for _, part := range parts {
+		uploadParts = append(uploadParts, oss.UploadPart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	_, err := ali.client.CompleteMultipartUpload(ali.imur(tenant, objKey, uploadID), uploadParts)
+	return aliyunError(err)
+}
+
+func (ali *AliYun) AbortMultipartUpload(tenant string, objKey string, uploadID string) error {
+	return aliyunError(ali.client.AbortMultipartUpload(ali.imur(tenant, objKey, uploadID)))
+}
+
+func (ali *AliYun) ListMultipartUploads(tenant string, prefix string) ([]MultipartUpload, error) {
+	tenantPrefix := tenant + "/"
+	var uploads []MultipartUpload
+	keyMarker, uploadIDMarker := "", ""
+	for {
+		ret, err := ali.client.ListMultipartUploads(oss.Prefix(tenantPrefix+prefix), oss.KeyMarker(keyMarker), oss.UploadIDMarker(uploadIDMarker))
+		if err != nil {
+			return nil, aliyunError(err)
+		}
+		for _, upload := range ret.Uploads {
+			uploads = append(uploads, MultipartUpload{
+				Key:       strings.TrimPrefix(upload.Key, tenantPrefix),
+				UploadID:  upload.UploadID,
+				Initiated: upload.Initiated,
+			})
+		}
+		if !ret.IsTruncated {
+			break
+		}
+		keyMarker, uploadIDMarker = ret.NextKeyMarker, ret.NextUploadIDMarker
+	}
+	return uploads, nil
+}
+
+func (ali *AliYun) imur(tenant string, objKey string, uploadID string) oss.InitiateMultipartUploadResult {
+	return oss.InitiateMultipartUploadResult{Bucket: ali.client.BucketName, Key: tenant + "/" + objKey, UploadID: uploadID}
+}
+
+func aliyunError(err error) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case oss.ServiceError:
+		return newError(err, e.StatusCode, e.Code)
+	case oss.UnexpectedStatusCodeError:
+		return newError(err, e.Got(), "")
+	default:
+		return newClientError(err)
+	}
 }
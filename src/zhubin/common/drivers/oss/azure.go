@@ -2,19 +2,28 @@ package oss
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"github.com/Azure/azure-sdk-for-go/storage"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// Block blobs take the properties when the block list is committed, so the options
+// of the uploads in progress are kept in blobs of their own under this directory until then,
+// out of the reach of the tenants, and survive a restart of the process.
+const azureUploadDir = ".ossuploads"
+
 type Azure struct {
 	client *storage.Container
 }
 
-var ErrMsgAzureObjectNotFound = "storage: service returned error: StatusCode=404, ErrorCode=BlobNotFound, ErrorMessage=The specified blob does not exist."
-
 func NewAzureClient(host, apiVersion, accountName, accountKey string, bucket string) *Azure {
 	if !validate(host, apiVersion, accountName, accountKey, bucket) {
 		panic("Uninitialized oss driver.")
@@ -37,60 +46,54 @@ func NewAzureClient(host, apiVersion, accountName, accountKey string, bucket str
 	return &Azure{client: container}
 }
 
-func (azure *Azure) PutObject(tenant string, objKey string, object io.Reader, overwrite bool) error {
+func (azure *Azure) PutObject(tenant string, objKey string, object io.Reader, overwrite bool, options ...PutOptions) error {
 	if !overwrite {
-		exist, err := azure.CheckExist(tenant, objKey)
-		if err != nil {
-			return err
-		}
-		if exist {
-			return nil
-		}
+		_, err := azure.ConditionalPutObject(tenant, objKey, object, PutCondition{IfNotExist: true}, options...)
+		return err
 	}
-	return azure.client.GetBlobReference(tenant+"/"+objKey).CreateBlockBlobFromReader(object, nil)
+	return azureError(azure.blobReference(tenant, objKey, putOptions(objKey, options)).CreateBlockBlobFromReader(object, nil))
 }
 
-func (azure *Azure) FPutObject(tenant string, objKey string, filePath string, overwrite bool) error {
-	if !overwrite {
-		exist, err := azure.CheckExist(tenant, objKey)
-		if err != nil {
-			return err
-		}
-		if exist {
-			return nil
-		}
-	}
+func (azure *Azure) FPutObject(tenant string, objKey string, filePath string, overwrite bool, options ...PutOptions) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
-	return azure.client.GetBlobReference(tenant+"/"+objKey).CreateBlockBlobFromReader(file, nil)
+	defer file.Close()
+	return azure.PutObject(tenant, objKey, file, overwrite, options...)
 }
 
-func (azure *Azure) BPutObject(tenant string, objKey string, objData []byte, overwrite bool) error {
-	if !overwrite {
-		exist, err := azure.CheckExist(tenant, objKey)
-		if err != nil {
-			return err
-		}
-		if exist {
-			return nil
-		}
-	}
-	return azure.client.GetBlobReference(tenant+"/"+objKey).CreateBlockBlobFromReader(bytes.NewReader(objData), nil)
+func (azure *Azure) BPutObject(tenant string, objKey string, objData []byte, overwrite bool, options ...PutOptions) error {
+	return azure.PutObject(tenant, objKey, bytes.NewReader(objData), overwrite, options...)
 }
 
 func (azure *Azure) GetObject(tenant string, objKey string) (io.ReadCloser, error) {
 	obj, err := azure.client.GetBlobReference(tenant + "/" + objKey).Get(nil)
-	if err != nil && strings.Contains(err.Error(), ErrMsgAzureObjectNotFound) {
-		return nil, ErrNotFound
+	if err != nil {
+		return nil, azureError(err)
 	}
-	return obj, err
+	return obj, nil
+}
+
+// A range ending at 0 can not be expressed by the sdk, so the end is enforced on the stream.
+func (azure *Azure) GetObjectRange(tenant string, objKey string, offset int64, length int64) (io.ReadCloser, error) {
+	if offset == 0 && length <= 0 {
+		return azure.GetObject(tenant, objKey)
+	}
+	blobRange := &storage.BlobRange{Start: uint64(offset)}
+	if length > 0 {
+		blobRange.End = uint64(offset + length - 1)
+	}
+	obj, err := azure.client.GetBlobReference(tenant + "/" + objKey).GetRange(&storage.GetBlobRangeOptions{Range: blobRange})
+	if err != nil {
+		return nil, azureError(err)
+	}
+	return limitRange(obj, length), nil
 }
 
 func (azure *Azure) RemoveObject(tenant string, objKey string) error {
-	err := azure.client.GetBlobReference(tenant + "/" + objKey).Delete(nil)
-	if err != nil && strings.Contains(err.Error(), ErrMsgAzureObjectNotFound) {
+	err := azureError(azure.client.GetBlobReference(tenant + "/" + objKey).Delete(nil))
+	if err == ErrNotFound {
 		return nil
 	}
 	return err
@@ -99,14 +102,280 @@ func (azure *Azure) RemoveObject(tenant string, objKey string) error {
 func (azure *Azure) ReadObject(tenant string, objKey string) ([]byte, error) {
 	obj, err := azure.client.GetBlobReference(tenant + "/" + objKey).Get(nil)
 	if err != nil {
-		if strings.Contains(err.Error(), ErrMsgAzureObjectNotFound) {
-			return nil, ErrNotFound
+		return nil, azureError(err)
+	}
+	defer obj.Close()
+	data, err := ioutil.ReadAll(obj)
+	if err != nil {
+		return nil, azureError(err)
+	}
+	return data, nil
+}
+
+func (azure *Azure) CheckExist(tenant string, objKey string) (bool, error) {
+	exist, err := azure.client.GetBlobReference(tenant + "/" + objKey).Exists()
+	return exist, azureError(err)
+}
+
+// Azure only resumes a listing from its own continuation token, so the keys up to the marker
+// of another driver are listed again and skipped.
+func (azure *Azure) ListObjects(tenant string, prefix string, marker string, limit int) (*ListObjectsResult, error) {
+	m, err := decodeMarker(marker)
+	if err != nil {
+		return nil, err
+	}
+	tenantPrefix := tenant + "/"
+	limit = listLimit(limit)
+	result := &ListObjectsResult{Objects: make([]ObjectInfo, 0, limit)}
+	token := m.Token
+	for {
+		ret, err := azure.client.ListBlobs(storage.ListBlobsParameters{
+			Prefix:     tenantPrefix + prefix,
+			Marker:     token,
+			MaxResults: uint(limit - len(result.Objects)),
+		})
+		if err != nil {
+			return nil, azureError(err)
+		}
+		for _, blob := range ret.Blobs {
+			key := strings.TrimPrefix(blob.Name, tenantPrefix)
+			if key <= m.Key {
+				continue
+			}
+			result.Objects = append(result.Objects, ObjectInfo{
+				Key:          key,
+				Size:         blob.Properties.ContentLength,
+				ETag:         trimETag(blob.Properties.Etag),
+				LastModified: time.Time(blob.Properties.LastModified),
+			})
+		}
+		token = ret.NextMarker
+		if token == "" || len(result.Objects) >= limit {
+			break
+		}
+	}
+	if token != "" {
+		result.NextMarker = encodeMarker(result.Objects[len(result.Objects)-1].Key, token)
+	}
+	return result, nil
+}
+
+func (azure *Azure) StatObject(tenant string, objKey string) (*ObjectStat, error) {
+	blob := azure.client.GetBlobReference(tenant + "/" + objKey)
+	if err := blob.GetProperties(nil); err != nil {
+		return nil, azureError(err)
+	}
+	metadata := make(map[string]string, len(blob.Metadata))
+	for k, v := range blob.Metadata {
+		metadata[strings.ToLower(k)] = v
+	}
+	return &ObjectStat{
+		ObjectInfo: ObjectInfo{
+			Key:          objKey,
+			Size:         blob.Properties.ContentLength,
+			ETag:         trimETag(blob.Properties.Etag),
+			LastModified: time.Time(blob.Properties.LastModified),
+		},
+		ContentType:        blob.Properties.ContentType,
+		ContentDisposition: blob.Properties.ContentDisposition,
+		CacheControl:       blob.Properties.CacheControl,
+		Metadata:           metadata,
+	}, nil
+}
+
+func (azure *Azure) blobReference(tenant string, objKey string, opts PutOptions) *storage.Blob {
+	blob := azure.client.GetBlobReference(tenant + "/" + objKey)
+	blob.Properties.ContentType = opts.ContentType
+	blob.Properties.ContentDisposition = opts.ContentDisposition
+	blob.Properties.CacheControl = opts.CacheControl
+	blob.Metadata = storage.BlobMetadata(opts.Metadata)
+	return blob
+}
+
+func (azure *Azure) PresignedURL(tenant string, objKey string, method string, expires time.Duration) (string, error) {
+	var permissions string
+	switch method {
+	case http.MethodGet:
+		permissions = "r"
+	case http.MethodPut:
+		permissions = "cw"
+	default:
+		return "", ErrInvalidMethod
+	}
+	return azure.client.GetBlobReference(tenant+"/"+objKey).GetSASURI(time.Now().Add(expires), permissions)
+}
+
+// NOTE the sdk does not report the ETag of the written blob, StatObject to get it.
+func (azure *Azure) ConditionalPutObject(tenant string, objKey string, object io.Reader, cond PutCondition, options ...PutOptions) (*PutResult, error) {
+	putOpts := &storage.PutBlobOptions{}
+	if cond.IfNotExist {
+		putOpts.IfNoneMatch = "*"
+	} else if cond.IfMatch != "" {
+		putOpts.IfMatch = `"` + cond.IfMatch + `"`
+	}
+	err := azure.blobReference(tenant, objKey, putOptions(objKey, options)).CreateBlockBlobFromReader(object, putOpts)
+	return conditionalResult(cond, "", azureError(err))
+}
+
+// Block blobs have no upload id, a random one is generated to prefix the block ids
+// of the upload, so that blocks of different uploads to the same blob never mix.
+func (azure *Azure) InitiateMultipartUpload(tenant string, objKey string, options ...PutOptions) (string, error) {
+	uploadID := newUploadID()
+	data, err := json.Marshal(putOptions(objKey, options))
+	if err != nil {
+		return "", err
+	}
+	if err := azure.uploadBlob(uploadID).CreateBlockBlobFromReader(bytes.NewReader(data), nil); err != nil {
+		return "", azureError(err)
+	}
+	return uploadID, nil
+}
+
+func (azure *Azure) uploadBlob(uploadID string) *storage.Blob {
+	return azure.client.GetBlobReference(azureUploadDir + "/" + uploadID)
+}
+
+// The options of an upload, ErrUploadNotFound if it was completed or aborted.
+func (azure *Azure) uploadOptions(uploadID string) (PutOptions, error) {
+	var opts PutOptions
+	obj, err := azure.uploadBlob(uploadID).Get(nil)
+	if err != nil {
+		if err = azureError(err); err == ErrNotFound {
+			return opts, ErrUploadNotFound
+		}
+		return opts, err
+	}
+	defer obj.Close()
+	err = json.NewDecoder(obj).Decode(&opts)
+	return opts, err
+}
+
+func (azure *Azure) UploadPart(tenant string, objKey string, uploadID string, partNumber int, part io.Reader, size int64) (*PartInfo, error) {
+	blockID := azureBlockID(uploadID, partNumber)
+	err := azure.client.GetBlobReference(tenant+"/"+objKey).PutBlockWithLength(blockID, uint64(size), part, nil)
+	if err != nil {
+		return nil, azureError(err)
+	}
+	return &PartInfo{PartNumber: partNumber, ETag: blockID, Size: size}, nil
+}
+
+func (azure *Azure) ListParts(tenant string, objKey string, uploadID string) ([]PartInfo, error) {
+	ret, err := azure.client.GetBlobReference(tenant+"/"+objKey).GetBlockList(storage.BlockListTypeUncommitted, nil)
+	if err != nil {
+		if err = azureError(err); err == ErrNotFound {
+			return nil, ErrUploadNotFound
 		}
 		return nil, err
 	}
-	return ioutil.ReadAll(obj)
+	var parts []PartInfo
+	for _, block := range ret.UncommittedBlocks {
+		id, partNumber, ok := parseAzureBlockID(block.Name)
+		if ok && id == uploadID {
+			parts = append(parts, PartInfo{PartNumber: partNumber, ETag: block.Name, Size: block.Size})
+		}
+	}
+	if len(parts) == 0 {
+		if _, err := azure.uploadOptions(uploadID); err != nil {
+			return nil, err
+		}
+	}
+	sortParts(parts)
+	return parts, nil
 }
 
-func (azure *Azure) CheckExist(tenant string, objKey string) (bool, error) {
-	return azure.client.GetBlobReference(tenant + "/" + objKey).Exists()
+func (azure *Azure) CompleteMultipartUpload(tenant string, objKey string, uploadID string, parts []PartInfo) error {
+	opts, err := azure.uploadOptions(uploadID)
+	if err != nil {
+		return err
+	}
+	blocks := make([]storage.Block, 0, len(parts))
+	for _, part := range parts {
+		blocks = append(blocks, storage.Block{ID: part.ETag, Status: storage.BlockStatusUncommitted})
+	}
+	if err := azure.blobReference(tenant, objKey, opts).PutBlockList(blocks, nil); err != nil {
+		return azureError(err)
+	}
+	_, err = azure.uploadBlob(uploadID).DeleteIfExists(nil)
+	return azureError(err)
+}
+
+// Uncommitted blocks can not be deleted, they are garbage collected by Azure after a week.
+func (azure *Azure) AbortMultipartUpload(tenant string, objKey string, uploadID string) error {
+	_, err := azure.uploadBlob(uploadID).DeleteIfExists(nil)
+	return azureError(err)
+}
+
+// NOTE it fetches the block list of every blob under the prefix, keep the prefix narrow.
+func (azure *Azure) ListMultipartUploads(tenant string, prefix string) ([]MultipartUpload, error) {
+	tenantPrefix := tenant + "/"
+	var uploads []MultipartUpload
+	marker := ""
+	for {
+		ret, err := azure.client.ListBlobs(storage.ListBlobsParameters{
+			Prefix:  tenantPrefix + prefix,
+			Marker:  marker,
+			Include: &storage.IncludeBlobDataset{UncommittedBlobs: true},
+		})
+		if err != nil {
+			return nil, azureError(err)
+		}
+		for _, blob := range ret.Blobs {
+			blocks, err := azure.client.GetBlobReference(blob.Name).GetBlockList(storage.BlockListTypeUncommitted, nil)
+			if err != nil {
+				return nil, azureError(err)
+			}
+			seen := make(map[string]bool)
+			for _, block := range blocks.UncommittedBlocks {
+				id, _, ok := parseAzureBlockID(block.Name)
+				if !ok || seen[id] {
+					continue
+				}
+				seen[id] = true
+				uploads = append(uploads, MultipartUpload{
+					Key:       strings.TrimPrefix(blob.Name, tenantPrefix),
+					UploadID:  id,
+					Initiated: time.Time(blob.Properties.LastModified),
+				})
+			}
+		}
+		if ret.NextMarker == "" {
+			break
+		}
+		marker = ret.NextMarker
+	}
+	return uploads, nil
+}
+
+// All block ids of a blob must have the same length.
+func azureBlockID(uploadID string, partNumber int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%06d", uploadID, partNumber)))
+}
+
+func parseAzureBlockID(blockID string) (string, int, bool) {
+	data, err := base64.StdEncoding.DecodeString(blockID)
+	if err != nil {
+		return "", 0, false
+	}
+	i := strings.LastIndex(string(data), "-")
+	if i < 0 {
+		return "", 0, false
+	}
+	partNumber, err := strconv.Atoi(string(data[i+1:]))
+	if err != nil {
+		return "", 0, false
+	}
+	return string(data[:i]), partNumber, true
+}
+
+func azureError(err error) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case storage.AzureStorageServiceError:
+		return newError(err, e.StatusCode, e.Code)
+	case storage.UnexpectedStatusCodeError:
+		return newError(err, e.Got(), "")
+	default:
+		return newClientError(err)
+	}
 }
@@ -2,7 +2,7 @@ package conf
 
 type OSS struct {
 	Platform        string
-	Host            string
+	Host            string // Root directory for Local
 	Port            string
 	AccessKeyID     string // AccountName for Azure blob
 	SecretAccessKey string // AccountKey for Azure blob
@@ -10,4 +10,14 @@ type OSS struct {
 	Bucket          string
 	Location        string
 	UseSSL          bool
+	// For Minio and S3, whether the server supports conditional puts (If-None-Match, If-Match).
+	ConditionalWrites bool
+
+	// Backends of Mirror, the first one is the primary.
+	Replicas []*OSS
+	// Number of replicas a write must succeed on for Mirror, 0 means all of them.
+	WriteQuorum int
+	// Backends of Tiered, Cache is usually Local.
+	Cache  *OSS
+	Remote *OSS
 }
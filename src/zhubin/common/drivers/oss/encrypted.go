@@ -0,0 +1,552 @@
+package oss
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Master keys of the tenants, which wrap the data keys of their objects.
+type MasterKeys interface {
+	// Id and value of the key new objects of the tenant are wrapped with.
+	// Returns ErrNoMasterKey if the objects of the tenant are not encrypted.
+	CurrentKey(tenant string) (string, []byte, error)
+	// Key by id, to unwrap the data keys of objects written before a rotation.
+	Key(tenant string, keyID string) ([]byte, error)
+}
+
+// Master keys kept in memory, load them from a secret store at start up.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[string]map[string][]byte
+	current map[string]string
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]map[string][]byte), current: make(map[string]string)}
+}
+
+// Add an AES key (16, 24 or 32 bytes) of the tenant and make it the current one.
+// Previous keys are kept to decrypt the objects wrapped with them.
+func (k *Keyring) AddKey(tenant string, keyID string, key []byte) error {
+	if _, err := aes.NewCipher(key); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.keys[tenant] == nil {
+		k.keys[tenant] = make(map[string][]byte)
+	}
+	k.keys[tenant][keyID] = key
+	k.current[tenant] = keyID
+	return nil
+}
+
+func (k *Keyring) CurrentKey(tenant string) (string, []byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keyID, ok := k.current[tenant]
+	if !ok {
+		return "", nil, ErrNoMasterKey
+	}
+	return keyID, k.keys[tenant][keyID], nil
+}
+
+func (k *Keyring) Key(tenant string, keyID string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[tenant][keyID]
+	if !ok {
+		return nil, ErrNoMasterKey
+	}
+	return key, nil
+}
+
+// User metadata describing how an object is encrypted.
+// Keys are plain lower case letters, which every platform accepts.
+const (
+	encMetaScheme = "csescheme"
+	encMetaKeyID  = "csekeyid"
+	encMetaKey    = "csekey"
+	encMetaNonce  = "csenonce"
+)
+
+const (
+	// Objects are split into segments sealed by AES-256-GCM one by one, so they can be streamed.
+	encScheme      = "AES256-GCM-64K"
+	encSegmentSize = 64 << 10
+	encTagSize     = 16
+	// The nonce of a segment is the prefix, the segment number and a flag set on the last segment,
+	// so segments can neither be reordered nor dropped from the end.
+	encPrefixSize = 7
+)
+
+// Encrypts the objects before they are sent to the underlying driver and decrypts them when read.
+// Every object has its own random data key, wrapped by the master key of the tenant
+// and stored in the object metadata. Tenants without a master key are stored in plain.
+// NOTE sizes in ListObjects are those of the encrypted data, StatObject reports the plain ones.
+// NOTE multipart uploads and presigned urls are not supported for encrypted tenants,
+// use FPutObject to upload big files, it streams the file as well.
+type Encrypted struct {
+	driver ObjectStorageDriver
+	keys   MasterKeys
+}
+
+func NewEncryptedClient(driver ObjectStorageDriver, keys MasterKeys) *Encrypted {
+	if driver == nil || keys == nil {
+		panic("Uninitialized oss driver.")
+	}
+	return &Encrypted{driver: driver, keys: keys}
+}
+
+func (e *Encrypted) encrypted(tenant string) (bool, error) {
+	_, _, err := e.keys.CurrentKey(tenant)
+	if err == ErrNoMasterKey {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Wrap the object with an encrypting reader and add the key to the metadata.
+func (e *Encrypted) encrypt(tenant string, objKey string, object io.Reader, options []PutOptions) (io.Reader, PutOptions, error) {
+	opts := putOptions(objKey, options)
+	keyID, masterKey, err := e.keys.CurrentKey(tenant)
+	if err == ErrNoMasterKey {
+		return object, opts, nil
+	}
+	if err != nil {
+		return nil, opts, err
+	}
+	dataKey := make([]byte, 32)
+	prefix := make([]byte, encPrefixSize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, opts, err
+	}
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, opts, err
+	}
+	wrapped, err := wrapKey(masterKey, dataKey)
+	if err != nil {
+		return nil, opts, err
+	}
+	aead, err := newSegmentCipher(dataKey)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	metadata := make(map[string]string, len(opts.Metadata)+4)
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	metadata[encMetaScheme] = encScheme
+	metadata[encMetaKeyID] = keyID
+	metadata[encMetaKey] = base64.StdEncoding.EncodeToString(wrapped)
+	metadata[encMetaNonce] = base64.StdEncoding.EncodeToString(prefix)
+	opts.Metadata = metadata
+
+	return &encryptReader{
+		src:    bufio.NewReaderSize(object, encSegmentSize),
+		aead:   aead,
+		prefix: prefix,
+		plain:  make([]byte, encSegmentSize),
+	}, opts, nil
+}
+
+// Get the data key and nonce prefix of an object, nil if it is not encrypted.
+func (e *Encrypted) dataKey(tenant string, metadata map[string]string) (cipher.AEAD, []byte, error) {
+	scheme, ok := metadata[encMetaScheme]
+	if !ok {
+		return nil, nil, nil
+	}
+	if scheme != encScheme {
+		return nil, nil, ErrDecrypt
+	}
+	masterKey, err := e.keys.Key(tenant, metadata[encMetaKeyID])
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(metadata[encMetaKey])
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	prefix, err := base64.StdEncoding.DecodeString(metadata[encMetaNonce])
+	if err != nil || len(prefix) != encPrefixSize {
+		return nil, nil, ErrDecrypt
+	}
+	dataKey, err := unwrapKey(masterKey, wrapped)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newSegmentCipher(dataKey)
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	return aead, prefix, nil
+}
+
+func (e *Encrypted) PutObject(tenant string, objKey string, object io.Reader, overwrite bool, options ...PutOptions) error {
+	reader, opts, err := e.encrypt(tenant, objKey, object, options)
+	if err != nil {
+		return err
+	}
+	return e.driver.PutObject(tenant, objKey, reader, overwrite, opts)
+}
+
+func (e *Encrypted) FPutObject(tenant string, objKey string, filePath string, overwrite bool, options ...PutOptions) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return e.PutObject(tenant, objKey, file, overwrite, options...)
+}
+
+func (e *Encrypted) BPutObject(tenant string, objKey string, objData []byte, overwrite bool, options ...PutOptions) error {
+	return e.PutObject(tenant, objKey, bytes.NewReader(objData), overwrite, options...)
+}
+
+// The metadata is fetched first to find the key of the object.
+// NOTE if the object is replaced in between, reading it fails with ErrDecrypt.
+func (e *Encrypted) GetObject(tenant string, objKey string) (io.ReadCloser, error) {
+	return e.GetObjectRange(tenant, objKey, 0, -1)
+}
+
+// Only the segments holding the range are fetched and decrypted.
+func (e *Encrypted) GetObjectRange(tenant string, objKey string, offset int64, length int64) (io.ReadCloser, error) {
+	stat, err := e.driver.StatObject(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, prefix, err := e.dataKey(tenant, stat.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if aead == nil {
+		return e.driver.GetObjectRange(tenant, objKey, offset, length)
+	}
+
+	size := encPlainSize(stat.Size)
+	if size < 0 {
+		return nil, ErrDecrypt
+	}
+	if offset > 0 && offset >= size {
+		return nil, ErrInvalidRange
+	}
+	end := size
+	if length > 0 && offset+length < size {
+		end = offset + length
+	}
+	first, last := offset/encSegmentSize, int64(0)
+	if end > 0 {
+		last = (end - 1) / encSegmentSize
+	}
+	const sealedSize = encSegmentSize + encTagSize
+	obj, err := e.driver.GetObjectRange(tenant, objKey, first*sealedSize, (last-first+1)*sealedSize)
+	if err != nil {
+		return nil, err
+	}
+	reader := &decryptReader{
+		src:         obj,
+		aead:        aead,
+		prefix:      prefix,
+		segment:     uint32(first),
+		lastSegment: uint32(encSegments(stat.Size) - 1),
+		sealed:      make([]byte, sealedSize),
+	}
+	if _, err := io.CopyN(ioutil.Discard, reader, offset-first*encSegmentSize); err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return readCloser{Reader: io.LimitReader(reader, end-offset), Closer: obj}, nil
+}
+
+// Number of segments of an encrypted object, an empty one still has a segment with the tag.
+func encSegments(sealedSize int64) int64 {
+	segments := (sealedSize + encSegmentSize + encTagSize - 1) / (encSegmentSize + encTagSize)
+	if segments == 0 {
+		segments = 1
+	}
+	return segments
+}
+
+func encPlainSize(sealedSize int64) int64 {
+	return sealedSize - encSegments(sealedSize)*encTagSize
+}
+
+func (e *Encrypted) ReadObject(tenant string, objKey string) ([]byte, error) {
+	obj, err := e.GetObject(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return ioutil.ReadAll(obj)
+}
+
+func (e *Encrypted) RemoveObject(tenant string, objKey string) error {
+	return e.driver.RemoveObject(tenant, objKey)
+}
+
+func (e *Encrypted) CheckExist(tenant string, objKey string) (bool, error) {
+	return e.driver.CheckExist(tenant, objKey)
+}
+
+func (e *Encrypted) StatObject(tenant string, objKey string) (*ObjectStat, error) {
+	stat, err := e.driver.StatObject(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := stat.Metadata[encMetaScheme]; !ok {
+		return stat, nil
+	}
+	stat.Size = encPlainSize(stat.Size)
+	for _, k := range []string{encMetaScheme, encMetaKeyID, encMetaKey, encMetaNonce} {
+		delete(stat.Metadata, k)
+	}
+	return stat, nil
+}
+
+func (e *Encrypted) ListObjects(tenant string, prefix string, marker string, limit int) (*ListObjectsResult, error) {
+	return e.driver.ListObjects(tenant, prefix, marker, limit)
+}
+
+func (e *Encrypted) PresignedURL(tenant string, objKey string, method string, expires time.Duration) (string, error) {
+	encrypted, err := e.encrypted(tenant)
+	if err != nil {
+		return "", err
+	}
+	if encrypted {
+		return "", ErrNotSupported
+	}
+	return e.driver.PresignedURL(tenant, objKey, method, expires)
+}
+
+func (e *Encrypted) ConditionalPutObject(tenant string, objKey string, object io.Reader, cond PutCondition, options ...PutOptions) (*PutResult, error) {
+	reader, opts, err := e.encrypt(tenant, objKey, object, options)
+	if err != nil {
+		return nil, err
+	}
+	return e.driver.ConditionalPutObject(tenant, objKey, reader, cond, opts)
+}
+
+// Wrap the data key of an object with the current master key of the tenant, after a rotation.
+// The data is not decrypted, but the object is uploaded again since the metadata can not be changed in place.
+// Returns false if the object is not encrypted, already uses the current key, or changed meanwhile.
+// NOTE on platforms without conditional overwrites (Aliyun, Minio without ConditionalWrites) a concurrent change may be lost.
+func (e *Encrypted) Rewrap(tenant string, objKey string) (bool, error) {
+	stat, err := e.driver.StatObject(tenant, objKey)
+	if err != nil {
+		return false, err
+	}
+	keyID, masterKey, err := e.keys.CurrentKey(tenant)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := stat.Metadata[encMetaScheme]; !ok || stat.Metadata[encMetaKeyID] == keyID {
+		return false, nil
+	}
+	oldKey, err := e.keys.Key(tenant, stat.Metadata[encMetaKeyID])
+	if err != nil {
+		return false, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(stat.Metadata[encMetaKey])
+	if err != nil {
+		return false, ErrDecrypt
+	}
+	dataKey, err := unwrapKey(oldKey, wrapped)
+	if err != nil {
+		return false, err
+	}
+	if wrapped, err = wrapKey(masterKey, dataKey); err != nil {
+		return false, err
+	}
+	stat.Metadata[encMetaKeyID] = keyID
+	stat.Metadata[encMetaKey] = base64.StdEncoding.EncodeToString(wrapped)
+	opts := PutOptions{
+		ContentType:        stat.ContentType,
+		ContentDisposition: stat.ContentDisposition,
+		CacheControl:       stat.CacheControl,
+		Metadata:           stat.Metadata,
+	}
+
+	obj, err := e.driver.GetObject(tenant, objKey)
+	if err != nil {
+		return false, err
+	}
+	defer obj.Close()
+	ret, err := e.driver.ConditionalPutObject(tenant, objKey, obj, PutCondition{IfMatch: stat.ETag}, opts)
+	if err == ErrNotSupported {
+		return true, e.driver.PutObject(tenant, objKey, obj, true, opts)
+	}
+	if err != nil {
+		return false, err
+	}
+	return ret.Written, nil
+}
+
+func (e *Encrypted) InitiateMultipartUpload(tenant string, objKey string, options ...PutOptions) (string, error) {
+	encrypted, err := e.encrypted(tenant)
+	if err != nil {
+		return "", err
+	}
+	if encrypted {
+		return "", ErrNotSupported
+	}
+	return e.driver.InitiateMultipartUpload(tenant, objKey, options...)
+}
+
+func (e *Encrypted) UploadPart(tenant string, objKey string, uploadID string, partNumber int, part io.Reader, size int64) (*PartInfo, error) {
+	return e.driver.UploadPart(tenant, objKey, uploadID, partNumber, part, size)
+}
+
+func (e *Encrypted) ListParts(tenant string, objKey string, uploadID string) ([]PartInfo, error) {
+	return e.driver.ListParts(tenant, objKey, uploadID)
+}
+
+func (e *Encrypted) CompleteMultipartUpload(tenant string, objKey string, uploadID string, parts []PartInfo) error {
+	return e.driver.CompleteMultipartUpload(tenant, objKey, uploadID, parts)
+}
+
+func (e *Encrypted) AbortMultipartUpload(tenant string, objKey string, uploadID string) error {
+	return e.driver.AbortMultipartUpload(tenant, objKey, uploadID)
+}
+
+func (e *Encrypted) ListMultipartUploads(tenant string, prefix string) ([]MultipartUpload, error) {
+	return e.driver.ListMultipartUploads(tenant, prefix)
+}
+
+// Seal a data key with AES-GCM, so that a tampered wrapped key is detected when unwrapped.
+// The wrapped key is the random nonce followed by the sealed key.
+func wrapKey(masterKey []byte, dataKey []byte) ([]byte, error) {
+	aead, err := newSegmentCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(dataKey)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, nil), nil
+}
+
+func unwrapKey(masterKey []byte, wrapped []byte) ([]byte, error) {
+	aead, err := newSegmentCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	dataKey, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return dataKey, nil
+}
+
+func newSegmentCipher(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func segmentNonce(prefix []byte, segment uint32, last bool) []byte {
+	nonce := make([]byte, encPrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encPrefixSize:], segment)
+	if last {
+		nonce[encPrefixSize+4] = 1
+	}
+	return nonce
+}
+
+// Read a full segment of plain data into buf, and tell whether it is the last one.
+func readSegment(src *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return n, false, err
+	}
+	if n < len(buf) {
+		return n, true, nil
+	}
+	if _, err := src.Peek(1); err != nil {
+		if err == io.EOF {
+			return n, true, nil
+		}
+		return n, false, err
+	}
+	return n, false, nil
+}
+
+type encryptReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	segment uint32
+	plain   []byte
+	sealed  []byte
+	out     []byte
+	done    bool
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, last, err := readSegment(r.src, r.plain)
+		if err != nil {
+			return 0, err
+		}
+		r.sealed = r.aead.Seal(r.sealed[:0], segmentNonce(r.prefix, r.segment, last), r.plain[:n], nil)
+		r.out = r.sealed
+		r.segment++
+		r.done = last
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// The last segment is known from the size of the object, since src may be a range of it.
+type decryptReader struct {
+	src         io.Reader
+	aead        cipher.AEAD
+	prefix      []byte
+	segment     uint32
+	lastSegment uint32
+	sealed      []byte
+	plain       []byte
+	out         []byte
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.segment > r.lastSegment {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.src, r.sealed)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		r.plain, err = r.aead.Open(r.plain[:0], segmentNonce(r.prefix, r.segment, r.segment == r.lastSegment), r.sealed[:n], nil)
+		if err != nil {
+			return 0, ErrDecrypt
+		}
+		r.out = r.plain
+		r.segment++
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
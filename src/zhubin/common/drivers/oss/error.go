@@ -1,9 +1,208 @@
 package oss
 
-import "errors"
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+)
 
 var ErrNotFound = errors.New("object not found")
 
+var ErrUploadNotFound = errors.New("multipart upload not found")
+
+var ErrNotSupported = errors.New("operation not supported by the oss platform")
+
+var ErrInvalidMethod = errors.New("presigned method must be GET or PUT")
+
+var ErrInvalidRange = errors.New("offset is beyond the end of the object")
+
+var ErrInvalidMarker = errors.New("list marker was not returned by ListObjects")
+
+var ErrInvalidKey = errors.New("object key must be relative and can not contain .. segments")
+
+var ErrNoMasterKey = errors.New("no master key for the tenant")
+
+var ErrDecrypt = errors.New("object can not be decrypted, it is corrupted or the key is wrong")
+
+// Category of the errors returned by the drivers, independent of the platform.
+type ErrorKind int
+
+const (
+	KindUnknown ErrorKind = iota
+	KindNotFound
+	KindAlreadyExists
+	KindAccessDenied
+	KindQuotaExceeded
+	// The condition of a conditional put does not hold.
+	KindPreconditionFailed
+	// Too many requests, retry later with backoff.
+	KindThrottled
+	// Network failures and server side errors, retrying may succeed.
+	KindTransient
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindAlreadyExists:
+		return "already exists"
+	case KindAccessDenied:
+		return "access denied"
+	case KindQuotaExceeded:
+		return "quota exceeded"
+	case KindPreconditionFailed:
+		return "precondition failed"
+	case KindThrottled:
+		return "throttled"
+	case KindTransient:
+		return "transient"
+	default:
+		return "unknown"
+	}
+}
+
+// Error of the platform sdk, classified.
+// NOTE a missing object is always reported as ErrNotFound, not as Error.
+type Error struct {
+	Kind       ErrorKind
+	StatusCode int    // http status code, 0 if unknown
+	Code       string // error code of the platform, e.g. NoSuchKey
+	Err        error  // the original error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Get the category of an error returned by the drivers.
+func KindOf(err error) ErrorKind {
+	switch err {
+	case nil:
+		return KindUnknown
+	case ErrNotFound, ErrUploadNotFound:
+		return KindNotFound
+	}
+	if e, ok := err.(*Error); ok {
+		return e.Kind
+	}
+	return KindUnknown
+}
+
 func IsNotFound(err error) bool {
-	return err == ErrNotFound
+	return KindOf(err) == KindNotFound
+}
+
+func IsAlreadyExists(err error) bool {
+	return KindOf(err) == KindAlreadyExists
+}
+
+func IsAccessDenied(err error) bool {
+	return KindOf(err) == KindAccessDenied
+}
+
+func IsQuotaExceeded(err error) bool {
+	return KindOf(err) == KindQuotaExceeded
+}
+
+func IsPreconditionFailed(err error) bool {
+	return KindOf(err) == KindPreconditionFailed
+}
+
+func IsThrottled(err error) bool {
+	return KindOf(err) == KindThrottled
+}
+
+func IsTransient(err error) bool {
+	return KindOf(err) == KindTransient
+}
+
+// Whether the same call may succeed if retried later.
+func IsRetryable(err error) bool {
+	kind := KindOf(err)
+	return kind == KindThrottled || kind == KindTransient
+}
+
+// Platform specific error codes which can not be told from the status code alone.
+var errorCodeKinds = map[string]ErrorKind{
+	// S3 & Minio
+	"NoSuchKey":                      KindNotFound,
+	"NoSuchBucket":                   KindNotFound,
+	"NoSuchUpload":                   KindNotFound,
+	"AccessDenied":                   KindAccessDenied,
+	"InvalidAccessKeyId":             KindAccessDenied,
+	"SignatureDoesNotMatch":          KindAccessDenied,
+	"BucketAlreadyExists":            KindAlreadyExists,
+	"QuotaExceeded":                  KindQuotaExceeded,
+	"XMinioStorageFull":              KindQuotaExceeded,
+	"XMinioAdminBucketQuotaExceeded": KindQuotaExceeded,
+	"PreconditionFailed":             KindPreconditionFailed,
+	"SlowDown":                       KindThrottled,
+	"ServiceUnavailable":             KindThrottled, // like the status 503, S3 asks to reduce the request rate
+	"RequestTimeout":                 KindTransient,
+	"InternalError":                  KindTransient,
+	// Aliyun
+	"FileAlreadyExists":   KindAlreadyExists,
+	"ObjectAlreadyExists": KindAlreadyExists,
+	"TooManyBuckets":      KindQuotaExceeded,
+	// Azure
+	"BlobNotFound":                   KindNotFound,
+	"ContainerNotFound":              KindNotFound,
+	"BlobAlreadyExists":              KindAlreadyExists,
+	"ContainerAlreadyExists":         KindAlreadyExists,
+	"AuthenticationFailed":           KindAccessDenied,
+	"AuthorizationFailure":           KindAccessDenied,
+	"InsufficientAccountPermissions": KindAccessDenied,
+	"AccountLimitExceeded":           KindQuotaExceeded,
+	"ConditionNotMet":                KindPreconditionFailed,
+	"ServerBusy":                     KindThrottled,
+	"OperationTimedOut":              KindTransient,
+}
+
+// Classify an error by the error code first, then by the http status code.
+func classify(statusCode int, code string) ErrorKind {
+	if kind, ok := errorCodeKinds[code]; ok {
+		return kind
+	}
+	switch {
+	case statusCode == http.StatusNotFound:
+		return KindNotFound
+	case statusCode == http.StatusConflict:
+		return KindAlreadyExists
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return KindAccessDenied
+	case statusCode == http.StatusRequestEntityTooLarge, statusCode == http.StatusInsufficientStorage:
+		return KindQuotaExceeded
+	case statusCode == http.StatusPreconditionFailed:
+		return KindPreconditionFailed
+	case statusCode == http.StatusTooManyRequests, statusCode == http.StatusServiceUnavailable:
+		return KindThrottled
+	case statusCode == http.StatusRequestTimeout, statusCode >= http.StatusInternalServerError:
+		return KindTransient
+	}
+	return KindUnknown
+}
+
+// Wrap an sdk error with its status code and error code.
+func newError(err error, statusCode int, code string) error {
+	if code == "NoSuchUpload" {
+		return ErrUploadNotFound
+	}
+	if code == "InvalidRange" || statusCode == http.StatusRequestedRangeNotSatisfiable {
+		return ErrInvalidRange
+	}
+	kind := classify(statusCode, code)
+	if kind == KindNotFound {
+		return ErrNotFound
+	}
+	return &Error{Kind: kind, StatusCode: statusCode, Code: code, Err: err}
+}
+
+// Errors which did not come from the service, e.g. the network is down.
+func newClientError(err error) error {
+	if _, ok := err.(net.Error); ok || err == io.ErrUnexpectedEOF {
+		return &Error{Kind: KindTransient, Err: err}
+	}
+	return err
 }
@@ -0,0 +1,595 @@
+package oss
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/afero"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// Suffix of the temporary files holding objects being written.
+	localTmpSuffix = ".ossuploading"
+	// Root directory of the object properties.
+	localMetaDir = ".ossmeta"
+	// Root directory of the multipart uploads in progress.
+	localUploadDir = ".ossuploads"
+	// Description of an upload, stored with its parts.
+	localUploadFile = "upload.json"
+)
+
+// Local filesystem storage, mainly for development and unit tests.
+// Objects are stored as plain files under <root>/<bucket>/<tenant>/<objKey>.
+type Local struct {
+	Bucket string
+	fs     afero.Fs
+
+	// Serializes the check and the rename of conditional puts.
+	putMu sync.Mutex
+}
+
+// Create a driver backed by the directory root on the local disk.
+func NewLocalClient(root string, bucket string) *Local {
+	if !validate(root, bucket) {
+		panic("Uninitialized oss driver.")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		panic(err)
+	}
+	return newLocal(afero.NewBasePathFs(afero.NewOsFs(), root), bucket)
+}
+
+// Create a driver which keeps every object in memory.
+// Data is lost when the process exits.
+func NewMemLocalClient(bucket string) *Local {
+	if !validate(bucket) {
+		panic("Uninitialized oss driver.")
+	}
+	return newLocal(afero.NewMemMapFs(), bucket)
+}
+
+func newLocal(fs afero.Fs, bucket string) *Local {
+	if err := fs.MkdirAll(bucket, 0755); err != nil {
+		panic(err)
+	}
+	return &Local{Bucket: bucket, fs: fs}
+}
+
+func (l *Local) objectPath(tenant string, objKey string) (string, error) {
+	return localPath(l.Bucket, tenant, objKey)
+}
+
+// Properties of objects are kept in json files under a separate tree,
+// so they never show up in ListObjects.
+func (l *Local) metaPath(tenant string, objKey string) (string, error) {
+	return localPath(path.Join(localMetaDir, l.Bucket), tenant, objKey)
+}
+
+// The path of root/dir/name, ErrInvalidKey if it is not under root/dir,
+// e.g. the key ../alice/x would reach the objects of the tenant alice.
+func localPath(root string, dir string, name string) (string, error) {
+	if !isLocalName(dir) || !isLocalName(name) {
+		return "", ErrInvalidKey
+	}
+	base := path.Join(root, dir)
+	p := path.Join(base, name)
+	if base == root || !isUnder(base, root) || !isUnder(p, base) {
+		return "", ErrInvalidKey
+	}
+	return filepath.FromSlash(p), nil
+}
+
+// Relative and without .. segments.
+func isLocalName(name string) bool {
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return false
+	}
+	segments := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '/' || r == filepath.Separator
+	})
+	for _, segment := range segments {
+		if segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func isUnder(p string, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+func (l *Local) PutObject(tenant string, objKey string, object io.Reader, overwrite bool, options ...PutOptions) error {
+	_, err := l.ConditionalPutObject(tenant, objKey, object, PutCondition{IfNotExist: !overwrite}, options...)
+	return err
+}
+
+func (l *Local) FPutObject(tenant string, objKey string, filePath string, overwrite bool, options ...PutOptions) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return l.PutObject(tenant, objKey, file, overwrite, options...)
+}
+
+func (l *Local) BPutObject(tenant string, objKey string, objData []byte, overwrite bool, options ...PutOptions) error {
+	return l.PutObject(tenant, objKey, bytes.NewReader(objData), overwrite, options...)
+}
+
+func (l *Local) GetObject(tenant string, objKey string) (io.ReadCloser, error) {
+	objPath, err := l.objectPath(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	file, err := l.fs.Open(objPath)
+	if err != nil {
+		return nil, localError(err)
+	}
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		file.Close()
+		return nil, ErrNotFound
+	}
+	return file, nil
+}
+
+func (l *Local) GetObjectRange(tenant string, objKey string, offset int64, length int64) (io.ReadCloser, error) {
+	obj, err := l.GetObject(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	file := obj.(afero.File)
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, localError(err)
+	}
+	if offset > 0 && offset >= info.Size() {
+		file.Close()
+		return nil, ErrInvalidRange
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, localError(err)
+	}
+	return limitRange(file, length), nil
+}
+
+func (l *Local) ReadObject(tenant string, objKey string) ([]byte, error) {
+	obj, err := l.GetObject(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return afero.ReadAll(obj)
+}
+
+func (l *Local) RemoveObject(tenant string, objKey string) error {
+	objPath, err := l.objectPath(tenant, objKey)
+	if err != nil {
+		return err
+	}
+	metaPath, err := l.metaPath(tenant, objKey)
+	if err != nil {
+		return err
+	}
+	err = l.fs.Remove(objPath)
+	if err != nil && !os.IsNotExist(err) {
+		return localError(err)
+	}
+	err = l.fs.Remove(metaPath)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return localError(err)
+}
+
+func (l *Local) CheckExist(tenant string, objKey string) (bool, error) {
+	objPath, err := l.objectPath(tenant, objKey)
+	if err != nil {
+		return false, err
+	}
+	info, err := l.fs.Stat(objPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, localError(err)
+	}
+	return !info.IsDir(), nil
+}
+
+// Write to a temporary file first and rename it afterwards,
+// so readers never see a partially written object.
+func (l *Local) writeObject(objPath string, object io.Reader) error {
+	tmpPath, err := l.writeTemp(objPath, object)
+	if err != nil {
+		return err
+	}
+	return l.fs.Rename(tmpPath, objPath)
+}
+
+// Write the object to a temporary file next to objPath and return its path.
+func (l *Local) writeTemp(objPath string, object io.Reader) (string, error) {
+	dir := filepath.Dir(objPath)
+	if err := l.fs.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	tmpPath := filepath.Join(dir, fmt.Sprintf(".%s.%d%s", filepath.Base(objPath), time.Now().UnixNano(), localTmpSuffix))
+	file, err := l.fs.Create(tmpPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(file, object); err != nil {
+		file.Close()
+		l.fs.Remove(tmpPath)
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		l.fs.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// The data is written before taking the lock, only the check and the rename are serialized.
+// NOTE the condition is only atomic among the callers sharing this Local,
+// other processes writing to the same root are not seen.
+func (l *Local) ConditionalPutObject(tenant string, objKey string, object io.Reader, cond PutCondition, options ...PutOptions) (*PutResult, error) {
+	objPath, err := l.objectPath(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	tmpPath, err := l.writeTemp(objPath, object)
+	if err != nil {
+		return nil, localError(err)
+	}
+	l.putMu.Lock()
+	defer l.putMu.Unlock()
+
+	info, err := l.fs.Stat(objPath)
+	if err != nil && !os.IsNotExist(err) {
+		l.fs.Remove(tmpPath)
+		return nil, localError(err)
+	}
+	exist := err == nil && !info.IsDir()
+	if cond.IfNotExist && exist || !cond.IfNotExist && cond.IfMatch != "" && (!exist || localETag(info) != cond.IfMatch) {
+		l.fs.Remove(tmpPath)
+		return &PutResult{}, nil
+	}
+	if err := l.fs.Rename(tmpPath, objPath); err != nil {
+		l.fs.Remove(tmpPath)
+		return nil, localError(err)
+	}
+	if err := l.writeMeta(tenant, objKey, putOptions(objKey, options)); err != nil {
+		return nil, localError(err)
+	}
+	info, err = l.fs.Stat(objPath)
+	if err != nil {
+		return nil, localError(err)
+	}
+	return &PutResult{Written: true, ETag: localETag(info)}, nil
+}
+
+func (l *Local) ListObjects(tenant string, prefix string, marker string, limit int) (*ListObjectsResult, error) {
+	m, err := decodeMarker(marker)
+	if err != nil {
+		return nil, err
+	}
+	root, err := l.objectPath(tenant, "")
+	if err != nil {
+		return nil, err
+	}
+	var objects []ObjectInfo
+	err = afero.Walk(l.fs, root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(info.Name(), localTmpSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) || key <= m.Key {
+			return nil
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			ETag:         localETag(info),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	result := &ListObjectsResult{Objects: objects}
+	if limit = listLimit(limit); len(objects) > limit {
+		result.Objects = objects[:limit]
+		result.NextMarker = encodeMarker(objects[limit-1].Key, "")
+	}
+	return result, nil
+}
+
+func (l *Local) StatObject(tenant string, objKey string) (*ObjectStat, error) {
+	objPath, err := l.objectPath(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	info, err := l.fs.Stat(objPath)
+	if err != nil {
+		return nil, localError(err)
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+	opts, err := l.readMeta(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectStat{
+		ObjectInfo: ObjectInfo{
+			Key:          objKey,
+			Size:         info.Size(),
+			ETag:         localETag(info),
+			LastModified: info.ModTime(),
+		},
+		ContentType:        opts.ContentType,
+		ContentDisposition: opts.ContentDisposition,
+		CacheControl:       opts.CacheControl,
+		Metadata:           opts.Metadata,
+	}, nil
+}
+
+func (l *Local) writeMeta(tenant string, objKey string, opts PutOptions) error {
+	metadata := make(map[string]string, len(opts.Metadata))
+	for k, v := range opts.Metadata {
+		metadata[strings.ToLower(k)] = v
+	}
+	opts.Metadata = metadata
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	metaPath, err := l.metaPath(tenant, objKey)
+	if err != nil {
+		return err
+	}
+	return l.writeObject(metaPath, bytes.NewReader(data))
+}
+
+// Objects written before their properties were kept fall back to the defaults.
+func (l *Local) readMeta(tenant string, objKey string) (PutOptions, error) {
+	opts := putOptions(objKey, nil)
+	opts.Metadata = make(map[string]string)
+	metaPath, err := l.metaPath(tenant, objKey)
+	if err != nil {
+		return opts, err
+	}
+	data, err := afero.ReadFile(l.fs, metaPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return opts, nil
+		}
+		return opts, err
+	}
+	err = json.Unmarshal(data, &opts)
+	return opts, err
+}
+
+// A plain file has no content hash, the size and modification time are
+// enough to tell whether it changed.
+func localETag(info os.FileInfo) string {
+	return fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+}
+
+// There is no server in front of the local storage to accept the requests.
+func (l *Local) PresignedURL(tenant string, objKey string, method string, expires time.Duration) (string, error) {
+	return "", ErrNotSupported
+}
+
+type localUpload struct {
+	Tenant    string
+	ObjKey    string
+	Options   PutOptions
+	Initiated time.Time
+
+	// directory of the description and the parts
+	dir string
+}
+
+func (l *Local) uploadDir(uploadID string) (string, error) {
+	return localPath(path.Join(localUploadDir, l.Bucket), uploadID, "")
+}
+
+func (l *Local) InitiateMultipartUpload(tenant string, objKey string, options ...PutOptions) (string, error) {
+	if _, err := l.objectPath(tenant, objKey); err != nil {
+		return "", err
+	}
+	uploadID := newUploadID()
+	dir, err := l.uploadDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(localUpload{
+		Tenant:    tenant,
+		ObjKey:    objKey,
+		Options:   putOptions(objKey, options),
+		Initiated: time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := l.writeObject(filepath.Join(dir, localUploadFile), bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+	return uploadID, nil
+}
+
+func (l *Local) UploadPart(tenant string, objKey string, uploadID string, partNumber int, part io.Reader, size int64) (*PartInfo, error) {
+	upload, err := l.readUpload(tenant, objKey, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	h := md5.New()
+	counter := &countingReader{Reader: io.TeeReader(io.LimitReader(part, size), h)}
+	if err := l.writeObject(filepath.Join(upload.dir, strconv.Itoa(partNumber)), counter); err != nil {
+		return nil, err
+	}
+	return &PartInfo{PartNumber: partNumber, ETag: hex.EncodeToString(h.Sum(nil)), Size: counter.n}, nil
+}
+
+func (l *Local) ListParts(tenant string, objKey string, uploadID string) ([]PartInfo, error) {
+	upload, err := l.readUpload(tenant, objKey, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := afero.ReadDir(l.fs, upload.dir)
+	if err != nil {
+		return nil, err
+	}
+	var parts []PartInfo
+	for _, info := range infos {
+		partNumber, err := strconv.Atoi(info.Name())
+		if err != nil {
+			continue
+		}
+		data, err := afero.ReadFile(l.fs, filepath.Join(upload.dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sum := md5.Sum(data)
+		parts = append(parts, PartInfo{PartNumber: partNumber, ETag: hex.EncodeToString(sum[:]), Size: info.Size()})
+	}
+	sortParts(parts)
+	return parts, nil
+}
+
+func (l *Local) CompleteMultipartUpload(tenant string, objKey string, uploadID string, parts []PartInfo) error {
+	upload, err := l.readUpload(tenant, objKey, uploadID)
+	if err != nil {
+		return err
+	}
+	objPath, err := l.objectPath(tenant, objKey)
+	if err != nil {
+		return err
+	}
+	readers := make([]io.Reader, 0, len(parts))
+	for _, part := range parts {
+		file, err := l.fs.Open(filepath.Join(upload.dir, strconv.Itoa(part.PartNumber)))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+	if err := l.writeObject(objPath, io.MultiReader(readers...)); err != nil {
+		return err
+	}
+	if err := l.writeMeta(tenant, objKey, upload.Options); err != nil {
+		return err
+	}
+	return l.fs.RemoveAll(upload.dir)
+}
+
+func (l *Local) AbortMultipartUpload(tenant string, objKey string, uploadID string) error {
+	upload, err := l.readUpload(tenant, objKey, uploadID)
+	if err != nil {
+		return err
+	}
+	return l.fs.RemoveAll(upload.dir)
+}
+
+func (l *Local) ListMultipartUploads(tenant string, prefix string) ([]MultipartUpload, error) {
+	infos, err := afero.ReadDir(l.fs, filepath.FromSlash(path.Join(localUploadDir, l.Bucket)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var uploads []MultipartUpload
+	for _, info := range infos {
+		upload, err := l.readUpload(tenant, "", info.Name())
+		if err != nil {
+			if err == ErrUploadNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if strings.HasPrefix(upload.ObjKey, prefix) {
+			uploads = append(uploads, MultipartUpload{Key: upload.ObjKey, UploadID: info.Name(), Initiated: upload.Initiated})
+		}
+	}
+	return uploads, nil
+}
+
+// An empty objKey matches any object of the tenant.
+func (l *Local) readUpload(tenant string, objKey string, uploadID string) (*localUpload, error) {
+	dir, err := l.uploadDir(uploadID)
+	if err != nil {
+		return nil, ErrUploadNotFound
+	}
+	data, err := afero.ReadFile(l.fs, filepath.Join(dir, localUploadFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	upload := &localUpload{dir: dir}
+	if err := json.Unmarshal(data, upload); err != nil {
+		return nil, err
+	}
+	if upload.Tenant != tenant || (objKey != "" && upload.ObjKey != objKey) {
+		return nil, ErrUploadNotFound
+	}
+	return upload, nil
+}
+
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func localError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case os.IsNotExist(err):
+		return ErrNotFound
+	case os.IsPermission(err):
+		return &Error{Kind: KindAccessDenied, Err: err}
+	}
+	if perr, ok := err.(*os.PathError); ok && (perr.Err == syscall.ENOSPC || perr.Err == syscall.EDQUOT) {
+		return &Error{Kind: KindQuotaExceeded, Err: err}
+	}
+	return err
+}
@@ -6,17 +6,26 @@ import (
 	"github.com/minio/minio-go"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Compatible with Amazon S3
 type Minio struct {
 	Bucket   string
 	Location string
-	client   *minio.Client
+	// Whether the server honours If-None-Match and If-Match on PUT, required by ConditionalPutObject.
+	// NOTE older Minio releases ignore the headers and overwrite the object.
+	ConditionalWrites bool
+	client            *minio.Client
+	core              minio.Core
 }
 
-var ErrMsgMinioObjectNotExists = "The specified key does not exist."
+const minioMetaPrefix = "X-Amz-Meta-"
 
 func NewMinioClient(host, port, accessKeyId, SecretAccressKey string, useSSL bool, bucket string, location string) *Minio {
 	if !validate(host, port, accessKeyId, SecretAccressKey, bucket) {
@@ -36,61 +45,86 @@ func NewMinioClient(host, port, accessKeyId, SecretAccressKey string, useSSL boo
 			panic(err)
 		}
 	}
-	return &Minio{Bucket: bucket, client: client}
+	return &Minio{Bucket: bucket, client: client, core: minio.Core{Client: client}}
 }
 
-func (m *Minio) PutObject(tenant string, objKey string, object io.Reader, overwrite bool) error {
+// NOTE without overwrite the existence is checked before the put, use ConditionalPutObject
+// to create the object atomically.
+func (m *Minio) PutObject(tenant string, objKey string, object io.Reader, overwrite bool, options ...PutOptions) error {
 	if !overwrite {
 		exist, err := m.CheckExist(tenant, objKey)
-		if err != nil {
+		if err != nil || exist {
 			return err
 		}
-		if exist {
-			return nil
-		}
 	}
-	_, err := m.client.PutObject(m.Bucket, tenant+"/"+objKey, object, "")
-	return err
+	opts := putOptions(objKey, options)
+	_, err := m.client.PutObjectWithMetadata(m.Bucket, tenant+"/"+objKey, object, minioMetadata(opts), nil)
+	return minioError(err)
 }
 
-func (m *Minio) FPutObject(tenant string, objKey string, filePath string, overwrite bool) error {
+func (m *Minio) FPutObject(tenant string, objKey string, filePath string, overwrite bool, options ...PutOptions) error {
 	if !overwrite {
 		exist, err := m.CheckExist(tenant, objKey)
-		if err != nil {
+		if err != nil || exist {
 			return err
 		}
-		if exist {
-			return nil
-		}
 	}
-	_, err := m.client.FPutObject(m.Bucket, tenant+"/"+objKey, filePath, "")
-	return err
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	opts := putOptions(objKey, options)
+	_, err = m.client.PutObjectWithSize(m.Bucket, tenant+"/"+objKey, file, info.Size(), minioMetadata(opts), nil)
+	return minioError(err)
 }
 
-func (m *Minio) BPutObject(tenant string, objKey string, objData []byte, overwrite bool) error {
-	if !overwrite {
-		exist, err := m.CheckExist(tenant, objKey)
-		if err != nil {
-			return err
-		}
-		if exist {
-			return nil
-		}
-	}
-	return m.PutObject(tenant, objKey, bytes.NewReader(objData), overwrite)
+func (m *Minio) BPutObject(tenant string, objKey string, objData []byte, overwrite bool, options ...PutOptions) error {
+	return m.PutObject(tenant, objKey, bytes.NewReader(objData), overwrite, options...)
 }
 
+// The object is fetched lazily by minio, stat it so that a missing object
+// is reported here instead of on the first read.
 func (m *Minio) GetObject(tenant string, objKey string) (io.ReadCloser, error) {
 	obj, err := m.client.GetObject(m.Bucket, tenant+"/"+objKey)
-	if err != nil && err.Error() == ErrMsgMinioObjectNotExists {
-		return nil, ErrNotFound
+	if err != nil {
+		return nil, minioError(err)
 	}
-	return obj, err
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, minioError(err)
+	}
+	return obj, nil
+}
+
+func (m *Minio) GetObjectRange(tenant string, objKey string, offset int64, length int64) (io.ReadCloser, error) {
+	if offset == 0 && length <= 0 {
+		return m.GetObject(tenant, objKey)
+	}
+	headers := minio.NewGetReqHeaders()
+	var err error
+	if length > 0 {
+		err = headers.SetRange(offset, offset+length-1)
+	} else {
+		err = headers.SetRange(offset, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	obj, _, err := m.core.GetObject(m.Bucket, tenant+"/"+objKey, headers)
+	if err != nil {
+		return nil, minioError(err)
+	}
+	return obj, nil
 }
 
 func (m *Minio) RemoveObject(tenant string, objKey string) error {
-	err := m.client.RemoveObject(m.Bucket, tenant+"/"+objKey)
-	if err != nil && err.Error() == ErrMsgMinioObjectNotExists {
+	err := minioError(m.client.RemoveObject(m.Bucket, tenant+"/"+objKey))
+	if err == ErrNotFound {
 		return nil
 	}
 	return err
@@ -99,21 +133,204 @@ func (m *Minio) RemoveObject(tenant string, objKey string) error {
 func (m *Minio) ReadObject(tenant string, objKey string) ([]byte, error) {
 	obj, err := m.client.GetObject(m.Bucket, tenant+"/"+objKey)
 	if err != nil {
-		if strings.Contains(err.Error(), ErrMsgMinioObjectNotExists) {
-			return nil, ErrNotFound
-		}
-		return nil, err
+		return nil, minioError(err)
 	}
-	return ioutil.ReadAll(obj)
+	defer obj.Close()
+	data, err := ioutil.ReadAll(obj)
+	if err != nil {
+		return nil, minioError(err)
+	}
+	return data, nil
 }
 
 func (m *Minio) CheckExist(tenant string, objKey string) (bool, error) {
 	_, err := m.client.StatObject(m.Bucket, tenant+"/"+objKey)
 	if err != nil {
-		if err.Error() == ErrMsgMinioObjectNotExists {
+		if err = minioError(err); err == ErrNotFound {
 			return false, nil
 		}
 		return false, err
 	}
 	return true, nil
 }
+
+func (m *Minio) ListObjects(tenant string, prefix string, marker string, limit int) (*ListObjectsResult, error) {
+	mk, err := decodeMarker(marker)
+	if err != nil {
+		return nil, err
+	}
+	tenantPrefix := tenant + "/"
+	if mk.Key != "" {
+		marker = tenantPrefix + mk.Key
+	}
+	ret, err := m.core.ListObjects(m.Bucket, tenantPrefix+prefix, marker, "", listLimit(limit))
+	if err != nil {
+		return nil, minioError(err)
+	}
+	result := &ListObjectsResult{Objects: make([]ObjectInfo, 0, len(ret.Contents))}
+	for _, obj := range ret.Contents {
+		result.Objects = append(result.Objects, ObjectInfo{
+			Key:          strings.TrimPrefix(obj.Key, tenantPrefix),
+			Size:         obj.Size,
+			ETag:         trimETag(obj.ETag),
+			LastModified: obj.LastModified,
+		})
+	}
+	if ret.IsTruncated && len(result.Objects) > 0 {
+		result.NextMarker = encodeMarker(result.Objects[len(result.Objects)-1].Key, "")
+	}
+	return result, nil
+}
+
+func (m *Minio) StatObject(tenant string, objKey string) (*ObjectStat, error) {
+	info, err := m.client.StatObject(m.Bucket, tenant+"/"+objKey)
+	if err != nil {
+		return nil, minioError(err)
+	}
+	return &ObjectStat{
+		ObjectInfo: ObjectInfo{
+			Key:          objKey,
+			Size:         info.Size,
+			ETag:         trimETag(info.ETag),
+			LastModified: info.LastModified,
+		},
+		ContentType:        info.ContentType,
+		ContentDisposition: info.Metadata.Get("Content-Disposition"),
+		CacheControl:       info.Metadata.Get("Cache-Control"),
+		Metadata:           metadataFromHeader(info.Metadata, minioMetaPrefix),
+	}, nil
+}
+
+func minioMetadata(opts PutOptions) map[string][]string {
+	metadata := map[string][]string{"Content-Type": {opts.ContentType}}
+	if opts.ContentDisposition != "" {
+		metadata["Content-Disposition"] = []string{opts.ContentDisposition}
+	}
+	if opts.CacheControl != "" {
+		metadata["Cache-Control"] = []string{opts.CacheControl}
+	}
+	for k, v := range opts.Metadata {
+		metadata[minioMetaPrefix+k] = []string{v}
+	}
+	return metadata
+}
+
+func (m *Minio) PresignedURL(tenant string, objKey string, method string, expires time.Duration) (string, error) {
+	var (
+		u   *url.URL
+		err error
+	)
+	switch method {
+	case http.MethodGet:
+		u, err = m.client.PresignedGetObject(m.Bucket, tenant+"/"+objKey, expires, nil)
+	case http.MethodPut:
+		u, err = m.client.PresignedPutObject(m.Bucket, tenant+"/"+objKey, expires)
+	default:
+		return "", ErrInvalidMethod
+	}
+	if err != nil {
+		return "", minioError(err)
+	}
+	return u.String(), nil
+}
+
+// Returns ErrNotSupported unless ConditionalWrites is set.
+// The object is sent in a single request, so it can not exceed 5GB, and it is read into memory if its size is unknown.
+func (m *Minio) ConditionalPutObject(tenant string, objKey string, object io.Reader, cond PutCondition, options ...PutOptions) (*PutResult, error) {
+	if !m.ConditionalWrites && (cond.IfNotExist || cond.IfMatch != "") {
+		return nil, ErrNotSupported
+	}
+	object, size, err := sizedReader(object)
+	if err != nil {
+		return nil, err
+	}
+	metadata := minioMetadata(putOptions(objKey, options))
+	if cond.IfNotExist {
+		metadata["If-None-Match"] = []string{"*"}
+	} else if cond.IfMatch != "" {
+		metadata["If-Match"] = []string{`"` + cond.IfMatch + `"`}
+	}
+	info, err := m.core.PutObject(m.Bucket, tenant+"/"+objKey, size, object, nil, nil, metadata)
+	return conditionalResult(cond, info.ETag, minioError(err))
+}
+
+func (m *Minio) InitiateMultipartUpload(tenant string, objKey string, options ...PutOptions) (string, error) {
+	uploadID, err := m.core.NewMultipartUpload(m.Bucket, tenant+"/"+objKey, minioMetadata(putOptions(objKey, options)))
+	return uploadID, minioError(err)
+}
+
+func (m *Minio) UploadPart(tenant string, objKey string, uploadID string, partNumber int, part io.Reader, size int64) (*PartInfo, error) {
+	objPart, err := m.core.PutObjectPart(m.Bucket, tenant+"/"+objKey, uploadID, partNumber, size, part, nil, nil)
+	if err != nil {
+		return nil, minioError(err)
+	}
+	return &PartInfo{PartNumber: objPart.PartNumber, ETag: trimETag(objPart.ETag), Size: objPart.Size}, nil
+}
+
+func (m *Minio) ListParts(tenant string, objKey string, uploadID string) ([]PartInfo, error) {
+	var parts []PartInfo
+	marker := 0
+	for {
+		ret, err := m.core.ListObjectParts(m.Bucket, tenant+"/"+objKey, uploadID, marker, 1000)
+		if err != nil {
+			return nil, minioError(err)
+		}
+		for _, part := range ret.ObjectParts {
+			parts = append(parts, PartInfo{PartNumber: part.PartNumber, ETag: trimETag(part.ETag), Size: part.Size})
+		}
+		if !ret.IsTruncated {
+			break
+		}
+		marker = ret.NextPartNumberMarker
+	}
+	return parts, nil
+}
+
+func (m *Minio) CompleteMultipartUpload(tenant string, objKey string, uploadID string, parts []PartInfo) error {
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	return minioError(m.core.CompleteMultipartUpload(m.Bucket, tenant+"/"+objKey, uploadID, completeParts))
+}
+
+func (m *Minio) AbortMultipartUpload(tenant string, objKey string, uploadID string) error {
+	return minioError(m.core.AbortMultipartUpload(m.Bucket, tenant+"/"+objKey, uploadID))
+}
+
+func (m *Minio) ListMultipartUploads(tenant string, prefix string) ([]MultipartUpload, error) {
+	tenantPrefix := tenant + "/"
+	var uploads []MultipartUpload
+	keyMarker, uploadIDMarker := "", ""
+	for {
+		ret, err := m.core.ListMultipartUploads(m.Bucket, tenantPrefix+prefix, keyMarker, uploadIDMarker, "", 1000)
+		if err != nil {
+			return nil, minioError(err)
+		}
+		for _, upload := range ret.Uploads {
+			uploads = append(uploads, MultipartUpload{
+				Key:       strings.TrimPrefix(upload.Key, tenantPrefix),
+				UploadID:  upload.UploadID,
+				Initiated: upload.Initiated,
+			})
+		}
+		if !ret.IsTruncated {
+			break
+		}
+		keyMarker, uploadIDMarker = ret.NextKeyMarker, ret.NextUploadIDMarker
+	}
+	return uploads, nil
+}
+
+// Responses without a body have the http status as error code, e.g. "503 Service Unavailable".
+func minioError(err error) error {
+	if err == nil {
+		return nil
+	}
+	resp, ok := err.(minio.ErrorResponse)
+	if !ok {
+		return newClientError(err)
+	}
+	statusCode, _ := strconv.Atoi(strings.SplitN(resp.Code, " ", 2)[0])
+	return newError(err, statusCode, resp.Code)
+}
@@ -0,0 +1,247 @@
+package oss
+
+import (
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Replicates every object to several backends.
+// Writes go to all the backends concurrently and succeed if at least WriteQuorum of them do,
+// reads and listings try the backends in order and fall back to the next one if the object can not be read.
+// The first backend is the primary, it serves the presigned urls and multipart uploads.
+type Mirror struct {
+	WriteQuorum int
+	backends    []ObjectStorageDriver
+}
+
+// writeQuorum <= 0 or greater than the number of backends means all of them.
+func NewMirrorClient(writeQuorum int, backends ...ObjectStorageDriver) *Mirror {
+	if len(backends) == 0 {
+		panic("Uninitialized oss driver.")
+	}
+	if writeQuorum <= 0 || writeQuorum > len(backends) {
+		writeQuorum = len(backends)
+	}
+	return &Mirror{WriteQuorum: writeQuorum, backends: backends}
+}
+
+func (m *Mirror) primary() ObjectStorageDriver {
+	return m.backends[0]
+}
+
+// Call write on all the backends concurrently, returns the first error if less than WriteQuorum succeed.
+func (m *Mirror) write(write func(driver ObjectStorageDriver) error) error {
+	errs := make([]error, len(m.backends))
+	var wg sync.WaitGroup
+	for i, driver := range m.backends {
+		wg.Add(1)
+		go func(i int, driver ObjectStorageDriver) {
+			defer wg.Done()
+			errs[i] = write(driver)
+		}(i, driver)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		logrus.Warnf("oss mirror: write to backend %d failed: %v", i, err)
+	}
+	if succeeded >= m.WriteQuorum {
+		return nil
+	}
+	return firstErr
+}
+
+// Call read on the backends in order until one succeeds.
+// If all of them fail, returns the first error other than ErrNotFound, or ErrNotFound.
+func (m *Mirror) read(read func(driver ObjectStorageDriver) error) error {
+	var firstErr error
+	for _, driver := range m.backends {
+		err := read(driver)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil || IsNotFound(firstErr) && !IsNotFound(err) {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// The stream can only be read once, so it is saved to a temporary file
+// which every backend uploads on its own.
+func (m *Mirror) PutObject(tenant string, objKey string, object io.Reader, overwrite bool, options ...PutOptions) error {
+	file, err := ioutil.TempFile("", "ossmirror")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := io.Copy(file, object); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return m.FPutObject(tenant, objKey, file.Name(), overwrite, options...)
+}
+
+func (m *Mirror) FPutObject(tenant string, objKey string, filePath string, overwrite bool, options ...PutOptions) error {
+	return m.write(func(driver ObjectStorageDriver) error {
+		return driver.FPutObject(tenant, objKey, filePath, overwrite, options...)
+	})
+}
+
+func (m *Mirror) BPutObject(tenant string, objKey string, objData []byte, overwrite bool, options ...PutOptions) error {
+	return m.write(func(driver ObjectStorageDriver) error {
+		return driver.BPutObject(tenant, objKey, objData, overwrite, options...)
+	})
+}
+
+func (m *Mirror) GetObject(tenant string, objKey string) (io.ReadCloser, error) {
+	var obj io.ReadCloser
+	err := m.read(func(driver ObjectStorageDriver) (err error) {
+		obj, err = driver.GetObject(tenant, objKey)
+		return err
+	})
+	return obj, err
+}
+
+func (m *Mirror) GetObjectRange(tenant string, objKey string, offset int64, length int64) (io.ReadCloser, error) {
+	var obj io.ReadCloser
+	err := m.read(func(driver ObjectStorageDriver) (err error) {
+		obj, err = driver.GetObjectRange(tenant, objKey, offset, length)
+		return err
+	})
+	return obj, err
+}
+
+func (m *Mirror) ReadObject(tenant string, objKey string) ([]byte, error) {
+	var data []byte
+	err := m.read(func(driver ObjectStorageDriver) (err error) {
+		data, err = driver.ReadObject(tenant, objKey)
+		return err
+	})
+	return data, err
+}
+
+func (m *Mirror) RemoveObject(tenant string, objKey string) error {
+	return m.write(func(driver ObjectStorageDriver) error {
+		return driver.RemoveObject(tenant, objKey)
+	})
+}
+
+// The object exists if any of the backends has it.
+func (m *Mirror) CheckExist(tenant string, objKey string) (bool, error) {
+	err := m.read(func(driver ObjectStorageDriver) error {
+		exist, err := driver.CheckExist(tenant, objKey)
+		if err == nil && !exist {
+			return ErrNotFound
+		}
+		return err
+	})
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (m *Mirror) StatObject(tenant string, objKey string) (*ObjectStat, error) {
+	var stat *ObjectStat
+	err := m.read(func(driver ObjectStorageDriver) (err error) {
+		stat, err = driver.StatObject(tenant, objKey)
+		return err
+	})
+	return stat, err
+}
+
+// Markers are understood by every backend, so a listing goes on with the next one when a backend fails.
+func (m *Mirror) ListObjects(tenant string, prefix string, marker string, limit int) (*ListObjectsResult, error) {
+	var result *ListObjectsResult
+	err := m.read(func(driver ObjectStorageDriver) (err error) {
+		result, err = driver.ListObjects(tenant, prefix, marker, limit)
+		return err
+	})
+	return result, err
+}
+
+func (m *Mirror) PresignedURL(tenant string, objKey string, method string, expires time.Duration) (string, error) {
+	return m.primary().PresignedURL(tenant, objKey, method, expires)
+}
+
+// Only the primary checks the condition, the object is copied from it to the other backends once written.
+func (m *Mirror) ConditionalPutObject(tenant string, objKey string, object io.Reader, cond PutCondition, options ...PutOptions) (*PutResult, error) {
+	ret, err := m.primary().ConditionalPutObject(tenant, objKey, object, cond, options...)
+	if err != nil || !ret.Written {
+		return ret, err
+	}
+	if err := m.replicate(tenant, objKey); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Copy an object from the primary to the other backends.
+func (m *Mirror) replicate(tenant string, objKey string) error {
+	stat, err := m.primary().StatObject(tenant, objKey)
+	if err != nil {
+		return err
+	}
+	opts := PutOptions{
+		ContentType:        stat.ContentType,
+		ContentDisposition: stat.ContentDisposition,
+		CacheControl:       stat.CacheControl,
+		Metadata:           stat.Metadata,
+	}
+	return m.write(func(driver ObjectStorageDriver) error {
+		if driver == m.primary() {
+			return nil
+		}
+		obj, err := m.primary().GetObject(tenant, objKey)
+		if err != nil {
+			return err
+		}
+		defer obj.Close()
+		return driver.PutObject(tenant, objKey, obj, true, opts)
+	})
+}
+
+// Multipart uploads are done on the primary only, and replicated when completed.
+func (m *Mirror) InitiateMultipartUpload(tenant string, objKey string, options ...PutOptions) (string, error) {
+	return m.primary().InitiateMultipartUpload(tenant, objKey, options...)
+}
+
+func (m *Mirror) UploadPart(tenant string, objKey string, uploadID string, partNumber int, part io.Reader, size int64) (*PartInfo, error) {
+	return m.primary().UploadPart(tenant, objKey, uploadID, partNumber, part, size)
+}
+
+func (m *Mirror) ListParts(tenant string, objKey string, uploadID string) ([]PartInfo, error) {
+	return m.primary().ListParts(tenant, objKey, uploadID)
+}
+
+func (m *Mirror) CompleteMultipartUpload(tenant string, objKey string, uploadID string, parts []PartInfo) error {
+	if err := m.primary().CompleteMultipartUpload(tenant, objKey, uploadID, parts); err != nil {
+		return err
+	}
+	return m.replicate(tenant, objKey)
+}
+
+func (m *Mirror) AbortMultipartUpload(tenant string, objKey string, uploadID string) error {
+	return m.primary().AbortMultipartUpload(tenant, objKey, uploadID)
+}
+
+func (m *Mirror) ListMultipartUploads(tenant string, prefix string) ([]MultipartUpload, error) {
+	return m.primary().ListMultipartUploads(tenant, prefix)
+}
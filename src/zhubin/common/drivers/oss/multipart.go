@@ -0,0 +1,202 @@
+package oss
+
+import (
+	"bytes"
+	"datamesh.com/common/utils/hash"
+	"datamesh.com/common/utils/randgen"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+)
+
+// Default size of a part in UploadFile, S3 requires at least 5MB for all parts but the last.
+const DefaultPartSize = 8 << 20
+
+type PartInfo struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+// An upload which is neither completed nor aborted.
+type MultipartUpload struct {
+	Key       string // Key without the tenant prefix
+	UploadID  string
+	Initiated time.Time
+}
+
+// Called after every part is uploaded or found uploaded before.
+type ProgressFunc func(uploaded int64, total int64)
+
+type UploadOptions struct {
+	// DefaultPartSize if <= 0.
+	PartSize int64
+	// File to keep the progress in. If the upload is interrupted, calling UploadFile
+	// again with the same file resumes it from the last uploaded part.
+	// Empty means the upload can not be resumed.
+	CheckpointFile string
+	Progress       ProgressFunc
+	PutOptions     PutOptions
+}
+
+type uploadCheckpoint struct {
+	Tenant   string
+	ObjKey   string
+	FilePath string
+	FileSize int64
+	ModTime  time.Time
+	PartSize int64
+	UploadID string
+	Parts    map[int]checkpointPart
+}
+
+type checkpointPart struct {
+	PartInfo
+	// Hash of the part, see hash.FileHash.Update
+	BlockHash string
+}
+
+// Upload a file to oss part by part, returns the hash of the whole file (see hash.FileHash).
+// Parts recorded in the checkpoint are verified by their block hash and skipped
+// if both the local data and the uploaded part are unchanged.
+func UploadFile(driver ObjectStorageDriver, tenant string, objKey string, filePath string, opts UploadOptions) (string, error) {
+	if opts.PartSize <= 0 {
+		opts.PartSize = DefaultPartSize
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	cp := loadCheckpoint(opts.CheckpointFile)
+	if cp == nil || cp.Tenant != tenant || cp.ObjKey != objKey || cp.FilePath != filePath ||
+		cp.FileSize != info.Size() || !cp.ModTime.Equal(info.ModTime()) || cp.PartSize != opts.PartSize {
+		cp = &uploadCheckpoint{
+			Tenant:   tenant,
+			ObjKey:   objKey,
+			FilePath: filePath,
+			FileSize: info.Size(),
+			ModTime:  info.ModTime(),
+			PartSize: opts.PartSize,
+		}
+	}
+
+	uploaded := make(map[int]PartInfo)
+	if cp.UploadID != "" {
+		parts, err := driver.ListParts(tenant, objKey, cp.UploadID)
+		if err != nil && err != ErrUploadNotFound {
+			return "", err
+		}
+		if err == ErrUploadNotFound {
+			cp.UploadID = ""
+		}
+		for _, part := range parts {
+			uploaded[part.PartNumber] = part
+		}
+	}
+	if cp.UploadID == "" {
+		cp.UploadID, err = driver.InitiateMultipartUpload(tenant, objKey, opts.PutOptions)
+		if err != nil {
+			return "", err
+		}
+		cp.Parts = make(map[int]checkpointPart)
+		if err := saveCheckpoint(opts.CheckpointFile, cp); err != nil {
+			return "", err
+		}
+	}
+
+	fileHash := hash.NewFileHash()
+	buf := make([]byte, opts.PartSize)
+	var parts []PartInfo
+	var done int64
+	for partNumber := 1; ; partNumber++ {
+		n, err := io.ReadFull(file, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		// an empty file still needs one part
+		if n == 0 && partNumber > 1 {
+			break
+		}
+		block := buf[:n]
+		blockHash, err := fileHash.Update(block)
+		if err != nil {
+			return "", err
+		}
+
+		part, ok := cp.Parts[partNumber]
+		remote, exist := uploaded[partNumber]
+		if !ok || !exist || part.BlockHash != blockHash || part.ETag != remote.ETag {
+			info, err := driver.UploadPart(tenant, objKey, cp.UploadID, partNumber, bytes.NewReader(block), int64(n))
+			if err != nil {
+				return "", err
+			}
+			part = checkpointPart{PartInfo: *info, BlockHash: blockHash}
+			cp.Parts[partNumber] = part
+			if err := saveCheckpoint(opts.CheckpointFile, cp); err != nil {
+				return "", err
+			}
+		}
+		parts = append(parts, part.PartInfo)
+
+		done += int64(n)
+		if opts.Progress != nil {
+			opts.Progress(done, cp.FileSize)
+		}
+		if int64(n) < opts.PartSize {
+			break
+		}
+	}
+
+	if err := driver.CompleteMultipartUpload(tenant, objKey, cp.UploadID, parts); err != nil {
+		return "", err
+	}
+	if opts.CheckpointFile != "" {
+		os.Remove(opts.CheckpointFile)
+	}
+	return fileHash.Sum(nil), nil
+}
+
+// A missing or broken checkpoint just means starting over.
+func loadCheckpoint(cpFile string) *uploadCheckpoint {
+	if cpFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(cpFile)
+	if err != nil {
+		return nil
+	}
+	cp := &uploadCheckpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil
+	}
+	return cp
+}
+
+func saveCheckpoint(cpFile string, cp *uploadCheckpoint) error {
+	if cpFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cpFile, data, 0644)
+}
+
+func sortParts(parts []PartInfo) {
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+}
+
+// Used by the platforms which have no upload id of their own.
+func newUploadID() string {
+	return randgen.GenRandString(16)
+}
@@ -1,9 +1,17 @@
 package oss
 
 import (
+	"bytes"
 	"datamesh.com/common/drivers/oss/conf"
+	"encoding/base64"
+	"encoding/json"
 	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
 	"strings"
+	"time"
 )
 
 var OssClient ObjectStorageDriver
@@ -15,15 +23,20 @@ func InitOssDriver(ossConfig *conf.OSS) {
 // oss storage driver
 type ObjectStorageDriver interface {
 	// Put an object into oss
-	PutObject(tenant string, objKey string, object io.Reader, override bool) error
+	PutObject(tenant string, objKey string, object io.Reader, override bool, options ...PutOptions) error
 	// Put an file into oss
-	FPutObject(tenant string, objKey string, filePath string, override bool) error
+	FPutObject(tenant string, objKey string, filePath string, override bool, options ...PutOptions) error
 	// Put binary data into oss
-	BPutObject(tenant string, objKey string, objData []byte, override bool) error
+	BPutObject(tenant string, objKey string, objData []byte, override bool, options ...PutOptions) error
 	// Get an object from oss
 	// if object not found, return oss.ErrNotFound as error.
 	// NOTE you need to close the stream yourself.
 	GetObject(tenant string, objKey string) (io.ReadCloser, error)
+	// Get length bytes of an object from offset, up to the end if length <= 0 or the object is shorter.
+	// if object not found, return oss.ErrNotFound, if offset is beyond the end, oss.ErrInvalidRange.
+	// See OpenObject for a seekable handle.
+	// NOTE you need to close the stream yourself.
+	GetObjectRange(tenant string, objKey string, offset int64, length int64) (io.ReadCloser, error)
 	// Read an object from oss to memory.
 	// if object not found, return oss.ErrNotFound as error.
 	// NOTE you should never use this method when the object is huge.
@@ -34,6 +47,98 @@ type ObjectStorageDriver interface {
 	// Check if the object exists in OSS.
 	// return value is valid if error is nil
 	CheckExist(tenant string, objKey string) (bool, error)
+	// Get the properties and user metadata of an object.
+	// if object not found, return oss.ErrNotFound as error.
+	StatObject(tenant string, objKey string) (*ObjectStat, error)
+	// List objects of the tenant whose key starts with prefix, in lexical order.
+	// Pass an empty marker for the first page, then ListObjectsResult.NextMarker
+	// to get the following ones. limit <= 0 means DefaultListLimit.
+	// Markers are opaque, but any driver resumes after the last key listed with the marker of another one.
+	// Returns ErrInvalidMarker if marker was not returned by ListObjects.
+	ListObjects(tenant string, prefix string, marker string, limit int) (*ListObjectsResult, error)
+	// Generate an url valid for expires, with which clients can download (http.MethodGet)
+	// or upload (http.MethodPut) the object without going through our services.
+	// NOTE uploads to Aliyun must carry the Content-Type detected from the key extension,
+	// and uploads to Azure must carry the header "x-ms-blob-type: BlockBlob".
+	PresignedURL(tenant string, objKey string, method string, expires time.Duration) (string, error)
+	// Put an object only if cond holds, checked atomically by the platform.
+	// A condition which does not hold is not an error, PutResult.Written is false then.
+	// Returns ErrNotSupported if the platform can not check cond.
+	ConditionalPutObject(tenant string, objKey string, object io.Reader, cond PutCondition, options ...PutOptions) (*PutResult, error)
+
+	// Multipart upload, use UploadFile unless you need to control every part yourself.
+	// Start an upload and return its id.
+	InitiateMultipartUpload(tenant string, objKey string, options ...PutOptions) (string, error)
+	// Upload a part of size bytes, partNumber starts from 1.
+	// Uploading the same partNumber again replaces the part.
+	UploadPart(tenant string, objKey string, uploadID string, partNumber int, part io.Reader, size int64) (*PartInfo, error)
+	// List the parts uploaded so far, ordered by part number.
+	// if upload not found, return oss.ErrUploadNotFound as error.
+	ListParts(tenant string, objKey string, uploadID string) ([]PartInfo, error)
+	// Concatenate the parts into the object in the given order.
+	CompleteMultipartUpload(tenant string, objKey string, uploadID string, parts []PartInfo) error
+	// Drop an upload and all its parts.
+	AbortMultipartUpload(tenant string, objKey string, uploadID string) error
+	// List the uploads of the tenant whose key starts with prefix.
+	ListMultipartUploads(tenant string, prefix string) ([]MultipartUpload, error)
+}
+
+const DefaultContentType = "application/octet-stream"
+
+// Default and max number of objects returned by one ListObjects call.
+const (
+	DefaultListLimit = 1000
+	MaxListLimit     = 1000
+)
+
+type ObjectInfo struct {
+	Key          string // Key without the tenant prefix
+	Size         int64
+	ETag         string
+	LastModified time.Time
+}
+
+// Optional attributes of an object, only the first one is used
+// when passed to the put methods.
+type PutOptions struct {
+	// Detected from the extension of objKey if empty.
+	ContentType        string
+	ContentDisposition string
+	CacheControl       string
+	// User defined metadata.
+	Metadata map[string]string
+}
+
+// Condition of ConditionalPutObject, set at most one of the fields.
+// The zero value puts the object unconditionally.
+type PutCondition struct {
+	// Only create the object, never overwrite an existing one.
+	IfNotExist bool
+	// Only overwrite the object if its current ETag is IfMatch, for compare-and-swap updates.
+	IfMatch string
+}
+
+type PutResult struct {
+	// False if the put was skipped because the condition did not hold.
+	Written bool
+	// ETag of the written object, empty if skipped or not reported by the platform.
+	ETag string
+}
+
+type ObjectStat struct {
+	ObjectInfo
+	ContentType        string
+	ContentDisposition string
+	CacheControl       string
+	// User defined metadata, keys are always in lower case.
+	Metadata map[string]string
+}
+
+type ListObjectsResult struct {
+	Objects []ObjectInfo
+	// Continuation token of the next page, empty if there are no more objects.
+	// It is opaque to the caller, only pass it back to ListObjects.
+	NextMarker string
 }
 
 //osType could be:ambry,ali...
@@ -48,7 +153,7 @@ func New(ossConfig *conf.OSS) ObjectStorageDriver {
 			ossConfig.Bucket,
 		)
 	case "Minio", "S3":
-		return NewMinioClient(
+		client := NewMinioClient(
 			ossConfig.Host,
 			ossConfig.Port,
 			ossConfig.AccessKeyID,
@@ -57,6 +162,8 @@ func New(ossConfig *conf.OSS) ObjectStorageDriver {
 			ossConfig.Bucket,
 			ossConfig.Location,
 		)
+		client.ConditionalWrites = ossConfig.ConditionalWrites
+		return client
 	case "Azure":
 		return NewAzureClient(
 			ossConfig.Host,
@@ -65,6 +172,21 @@ func New(ossConfig *conf.OSS) ObjectStorageDriver {
 			ossConfig.SecretAccessKey,
 			ossConfig.Bucket,
 		)
+	case "Local":
+		return NewLocalClient(ossConfig.Host, ossConfig.Bucket)
+	case "Memory":
+		return NewMemLocalClient(ossConfig.Bucket)
+	case "Mirror":
+		backends := make([]ObjectStorageDriver, 0, len(ossConfig.Replicas))
+		for _, replica := range ossConfig.Replicas {
+			backends = append(backends, New(replica))
+		}
+		return NewMirrorClient(ossConfig.WriteQuorum, backends...)
+	case "Tiered":
+		if ossConfig.Cache == nil || ossConfig.Remote == nil {
+			panic("Uninitialized oss driver.")
+		}
+		return NewTieredClient(New(ossConfig.Cache), New(ossConfig.Remote))
 
 	default:
 		panic("Unsupported platform: " + ossConfig.Platform)
@@ -72,6 +194,110 @@ func New(ossConfig *conf.OSS) ObjectStorageDriver {
 	return nil
 }
 
+func putOptions(objKey string, options []PutOptions) PutOptions {
+	var opts PutOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = mime.TypeByExtension(path.Ext(objKey))
+		if opts.ContentType == "" {
+			opts.ContentType = DefaultContentType
+		}
+	}
+	return opts
+}
+
+// Extract user metadata from response headers with the given prefix,
+// e.g. X-Amz-Meta-.
+func metadataFromHeader(header map[string][]string, prefix string) map[string]string {
+	metadata := make(map[string]string)
+	prefix = strings.ToLower(prefix)
+	for k, v := range header {
+		k = strings.ToLower(k)
+		if len(v) > 0 && strings.HasPrefix(k, prefix) {
+			metadata[strings.TrimPrefix(k, prefix)] = v[0]
+		}
+	}
+	return metadata
+}
+
+func listLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		return MaxListLimit
+	}
+	return limit
+}
+
+// Position of a listing, encoded in ListObjectsResult.NextMarker.
+// Every driver can resume after Key, Token is a continuation token of the platform, if any.
+type listMarker struct {
+	Key   string `json:"k"`
+	Token string `json:"t,omitempty"`
+}
+
+func encodeMarker(key string, token string) string {
+	data, _ := json.Marshal(listMarker{Key: key, Token: token})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// the zero listMarker for the first page
+func decodeMarker(marker string) (listMarker, error) {
+	var m listMarker
+	if marker == "" {
+		return m, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(marker)
+	if err != nil || json.Unmarshal(data, &m) != nil || m.Key == "" {
+		return listMarker{}, ErrInvalidMarker
+	}
+	return m, nil
+}
+
+func trimETag(etag string) string {
+	return strings.Trim(etag, "\"")
+}
+
+// Turn the error of a conditional put into its result.
+func conditionalResult(cond PutCondition, etag string, err error) (*PutResult, error) {
+	switch {
+	case err == nil:
+		return &PutResult{Written: true, ETag: trimETag(etag)}, nil
+	case IsPreconditionFailed(err),
+		cond.IfNotExist && IsAlreadyExists(err),
+		cond.IfMatch != "" && IsNotFound(err):
+		return &PutResult{}, nil
+	}
+	return nil, err
+}
+
+// Platforms putting objects in a single request need the size up front,
+// readers of unknown size are read into memory.
+func sizedReader(r io.Reader) (io.Reader, int64, error) {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return r, int64(v.Len()), nil
+	case *os.File:
+		info, err := v.Stat()
+		if err != nil {
+			return nil, 0, err
+		}
+		offset, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+		return r, info.Size() - offset, nil
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
 func validate(fields ...string) bool {
 	for _, v := range fields {
 		if strings.TrimSpace(v) == "" {
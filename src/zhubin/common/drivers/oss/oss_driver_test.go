@@ -1,14 +1,32 @@
 package oss
 
 import (
+	"bytes"
 	"datamesh.com/MeshExpert/config"
 	"datamesh.com/common/drivers/oss/conf"
+	"datamesh.com/common/utils/hash"
 	"datamesh.com/common/utils/randgen"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"github.com/Azure/azure-sdk-for-go/storage"
+	aliyun "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/minio/minio-go"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var (
@@ -166,6 +184,175 @@ func TestAliYun_GetObject(t *testing.T) {
 	fmt.Println(obj)
 }
 
+func TestAliYun_PresignedURL(t *testing.T) {
+	client, err := aliyun.New("oss-cn-beijing.aliyuncs.com", "LTAIjrFhcnc3Rg34", "0Bn2h6DIDMpN3LTfbDxlLYNU6GPKYD")
+	assert.Nil(t, err)
+	bkt, err := client.Bucket("me-holocloud-image")
+	assert.Nil(t, err)
+	ali := &AliYun{client: bkt}
+
+	rawURL, err := ali.PresignedURL(tenant, "a b.png", http.MethodPut, time.Hour)
+	assert.Nil(t, err)
+	u, err := url.Parse(rawURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "me-holocloud-image.oss-cn-beijing.aliyuncs.com", u.Host)
+	assert.Equal(t, "/"+tenant+"/a b.png", u.Path)
+	assert.Equal(t, "LTAIjrFhcnc3Rg34", u.Query().Get("OSSAccessKeyId"))
+	assert.NotEmpty(t, u.Query().Get("Signature"))
+
+	_, err = ali.PresignedURL(tenant, "a.png", http.MethodDelete, time.Hour)
+	assert.Equal(t, ErrInvalidMethod, err)
+}
+
+func TestAliYun_ConditionalPutObject(t *testing.T) {
+	var objects = map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "text/plain; charset=utf-8", r.Header.Get("Content-Type"))
+		if r.Header.Get("X-Oss-Forbid-Overwrite") == "true" && objects[r.URL.Path] {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>FileAlreadyExists</Code></Error>`)
+			return
+		}
+		objects[r.URL.Path] = true
+		w.Header().Set("ETag", `"5B3C1A2E053D763E1B002CC607C5A0FE"`)
+	}))
+	defer server.Close()
+	client, err := aliyun.New(server.URL, "LTAIjrFhcnc3Rg34", "0Bn2h6DIDMpN3LTfbDxlLYNU6GPKYD")
+	assert.Nil(t, err)
+	bkt, err := client.Bucket("me-holocloud-image")
+	assert.Nil(t, err)
+	ali := &AliYun{client: bkt}
+
+	ret, err := ali.ConditionalPutObject(tenant, "a.txt", strings.NewReader("v1"), PutCondition{IfNotExist: true})
+	assert.Nil(t, err)
+	assert.Equal(t, &PutResult{Written: true, ETag: "5B3C1A2E053D763E1B002CC607C5A0FE"}, ret)
+	ret, err = ali.ConditionalPutObject(tenant, "a.txt", strings.NewReader("v2"), PutCondition{IfNotExist: true})
+	assert.Nil(t, err)
+	assert.False(t, ret.Written)
+
+	_, err = ali.ConditionalPutObject(tenant, "a.txt", strings.NewReader("v2"), PutCondition{IfMatch: "5B3C1A2E053D763E1B002CC607C5A0FE"})
+	assert.Equal(t, ErrNotSupported, err)
+}
+
+func TestMinio_ConditionalPutObject(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{}
+	var puts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		data, exist := objects[r.URL.Path]
+		switch r.Method {
+		case http.MethodHead:
+			if !exist {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("ETag", `"5B3C1A2E053D763E1B002CC607C5A0FE"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		case http.MethodPut:
+			puts = append(puts, r.Header.Get("If-None-Match"))
+			if r.Header.Get("If-None-Match") == "*" && exist {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>PreconditionFailed</Code></Error>`)
+				return
+			}
+			// the body is signed in chunks
+			body, _ := ioutil.ReadAll(r.Body)
+			objects[r.URL.Path] = string(body)
+			w.Header().Set("ETag", `"5B3C1A2E053D763E1B002CC607C5A0FE"`)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	defer server.Close()
+	client, err := minio.NewWithRegion(strings.TrimPrefix(server.URL, "http://"), "KVQVTQKMM34JATV3G327", "8Vh7fS2eNc0pVqYCzv2IqWf3kP+arLfDw3vwAcrz", false, "us-east-1")
+	assert.Nil(t, err)
+	m := &Minio{Bucket: "testbucket", client: client, core: minio.Core{Client: client}}
+
+	// the server may ignore the headers, so the puts check the existence first
+	_, err = m.ConditionalPutObject(tenant, "a.txt", strings.NewReader("v1"), PutCondition{IfNotExist: true})
+	assert.Equal(t, ErrNotSupported, err)
+	assert.Nil(t, m.BPutObject(tenant, "a.txt", []byte("v1"), false))
+	assert.Nil(t, m.BPutObject(tenant, "a.txt", []byte("v2"), false))
+	assert.Contains(t, objects["/testbucket/"+tenant+"/a.txt"], "v1")
+	assert.Equal(t, []string{""}, puts)
+
+	m.ConditionalWrites = true
+	ret, err := m.ConditionalPutObject(tenant, "a.txt", strings.NewReader("v2"), PutCondition{IfNotExist: true})
+	assert.Nil(t, err)
+	assert.False(t, ret.Written)
+	ret, err = m.ConditionalPutObject(tenant, "b.txt", strings.NewReader("v1"), PutCondition{IfNotExist: true})
+	assert.Nil(t, err)
+	assert.Equal(t, &PutResult{Written: true, ETag: "5B3C1A2E053D763E1B002CC607C5A0FE"}, ret)
+	assert.Equal(t, []string{"", "*", "*"}, puts)
+}
+
+func TestAliYun_GetObjectRange(t *testing.T) {
+	data := "0123456789"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil || r.Header.Get("X-Oss-Range-Behavior") != "standard" {
+			// OSS returns the whole object if the range is invalid, unless asked for the standard behavior
+			fmt.Fprint(w, data)
+			return
+		}
+		if start >= len(data) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>InvalidRange</Code><Message>The requested range cannot be satisfied</Message></Error>`)
+			return
+		}
+		if end >= len(data) {
+			end = len(data) - 1
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		fmt.Fprint(w, data[start:end+1])
+	}))
+	defer server.Close()
+	client, err := aliyun.New(server.URL, "LTAIjrFhcnc3Rg34", "0Bn2h6DIDMpN3LTfbDxlLYNU6GPKYD")
+	assert.Nil(t, err)
+	bkt, err := client.Bucket("me-holocloud-image")
+	assert.Nil(t, err)
+	ali := &AliYun{client: bkt}
+
+	obj, err := ali.GetObjectRange(tenant, "a.txt", 3, 4)
+	assert.Nil(t, err)
+	ret, err := ioutil.ReadAll(obj)
+	obj.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "3456", string(ret))
+	obj, err = ali.GetObjectRange(tenant, "a.txt", 8, 4)
+	assert.Nil(t, err)
+	ret, err = ioutil.ReadAll(obj)
+	obj.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "89", string(ret))
+	_, err = ali.GetObjectRange(tenant, "a.txt", 10, 4)
+	assert.Equal(t, ErrInvalidRange, err)
+}
+
+func TestAliYun_StatObject(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+	}))
+	defer server.Close()
+	client, err := aliyun.New(server.URL, "LTAIjrFhcnc3Rg34", "0Bn2h6DIDMpN3LTfbDxlLYNU6GPKYD")
+	assert.Nil(t, err)
+	bkt, err := client.Bucket("me-holocloud-image")
+	assert.Nil(t, err)
+	ali := &AliYun{client: bkt}
+
+	_, err = ali.StatObject(tenant, "missing.txt")
+	assert.Equal(t, ErrNotFound, err)
+}
+
 func TestAliYun(t *testing.T) {
 
 	bucket = "me-holocloud-image"
@@ -286,3 +473,805 @@ func TestAzure(t *testing.T) {
 	err = OssClient.RemoveObject(tenant, objKey)
 	assert.Nil(t, err)
 }
+
+func TestLocal(t *testing.T) {
+	bucket = "localtest"
+	initConfig("Memory", "", "", "", "", bucket, false)
+	InitOssDriver(config.CONFIGS.OSS)
+
+	objKey := randgen.GenUniqueString(8)
+	ret, err := OssClient.GetObject(tenant, objKey)
+	assert.Equal(t, ErrNotFound, err)
+	exist, err := OssClient.CheckExist(tenant, objKey)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	reader := strings.NewReader(objKey)
+	err = OssClient.PutObject(tenant, objKey, reader, true)
+	assert.Nil(t, err)
+
+	ret, err = OssClient.GetObject(tenant, objKey)
+	assert.Nil(t, err)
+	data, err := ioutil.ReadAll(ret)
+	assert.Nil(t, err)
+	assert.Equal(t, objKey, string(data))
+	ret.Close()
+
+	// do not overwrite
+	err = OssClient.BPutObject(tenant, objKey, []byte(randgen.GenUniqueString(8)), false)
+	assert.Nil(t, err)
+	data, err = OssClient.ReadObject(tenant, objKey)
+	assert.Nil(t, err)
+	assert.Equal(t, objKey, string(data))
+
+	// other tenants can not see the object
+	exist, err = OssClient.CheckExist("ttt", objKey)
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	err = OssClient.RemoveObject(tenant, objKey)
+	assert.Nil(t, err)
+	_, err = OssClient.ReadObject(tenant, objKey)
+	assert.Equal(t, ErrNotFound, err)
+	err = OssClient.RemoveObject(tenant, objKey)
+	assert.Nil(t, err)
+}
+
+func TestLocal_ListObjects(t *testing.T) {
+	bucket = "localtest"
+	initConfig("Memory", "", "", "", "", bucket, false)
+	InitOssDriver(config.CONFIGS.OSS)
+
+	ret, err := OssClient.ListObjects(tenant, "", "", 0)
+	assert.Nil(t, err)
+	assert.Empty(t, ret.Objects)
+
+	keys := []string{"a/1", "a/2", "a/3", "b/1", "c"}
+	for _, key := range keys {
+		assert.Nil(t, OssClient.BPutObject(tenant, key, []byte(key), true))
+	}
+	assert.Nil(t, OssClient.BPutObject("ttt", "a/4", []byte("a/4"), true))
+
+	var listed []string
+	marker := ""
+	for {
+		ret, err = OssClient.ListObjects(tenant, "", marker, 2)
+		assert.Nil(t, err)
+		for _, obj := range ret.Objects {
+			listed = append(listed, obj.Key)
+			assert.Equal(t, int64(len(obj.Key)), obj.Size)
+		}
+		if ret.NextMarker == "" {
+			break
+		}
+		marker = ret.NextMarker
+	}
+	assert.Equal(t, keys, listed)
+
+	ret, err = OssClient.ListObjects(tenant, "a/", "", 0)
+	assert.Nil(t, err)
+	assert.Len(t, ret.Objects, 3)
+	assert.Equal(t, "", ret.NextMarker)
+
+	// opaque
+	ret, err = OssClient.ListObjects(tenant, "", "", 2)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "a/2", ret.NextMarker)
+	_, err = OssClient.ListObjects(tenant, "", "a/2", 2)
+	assert.Equal(t, ErrInvalidMarker, err)
+}
+
+func TestLocal_InvalidKey(t *testing.T) {
+	driver := NewMemLocalClient("localtest")
+	assert.Nil(t, driver.BPutObject("alice", "x", []byte("alice"), true, PutOptions{ContentType: "text/plain"}))
+
+	for _, key := range []string{"../alice/x", "a/../../alice/x", "/alice/x", ".."} {
+		_, err := driver.ReadObject(tenant, key)
+		assert.Equal(t, ErrInvalidKey, err, key)
+		_, err = driver.StatObject(tenant, key)
+		assert.Equal(t, ErrInvalidKey, err, key)
+		assert.Equal(t, ErrInvalidKey, driver.BPutObject(tenant, key, []byte("bob"), true), key)
+		assert.Equal(t, ErrInvalidKey, driver.RemoveObject(tenant, key), key)
+		_, err = driver.InitiateMultipartUpload(tenant, key)
+		assert.Equal(t, ErrInvalidKey, err, key)
+	}
+	for _, other := range []string{"", "..", "../localtest/alice", "bob/../alice"} {
+		_, err := driver.ReadObject(other, "x")
+		assert.Equal(t, ErrInvalidKey, err, other)
+	}
+	_, err := driver.ListParts(tenant, "x", "../../localtest/alice")
+	assert.Equal(t, ErrUploadNotFound, err)
+
+	// a key may still contain dots
+	assert.Nil(t, driver.BPutObject(tenant, "a/..b/./c..", []byte("bob"), true))
+	data, err := driver.ReadObject(tenant, "a/..b/c..")
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", string(data))
+
+	data, err = driver.ReadObject("alice", "x")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", string(data))
+	stat, err := driver.StatObject("alice", "x")
+	assert.Nil(t, err)
+	assert.Equal(t, "text/plain", stat.ContentType)
+}
+
+func TestLocal_StatObject(t *testing.T) {
+	bucket = "localtest"
+	initConfig("Memory", "", "", "", "", bucket, false)
+	InitOssDriver(config.CONFIGS.OSS)
+
+	_, err := OssClient.StatObject(tenant, "a.png")
+	assert.Equal(t, ErrNotFound, err)
+
+	err = OssClient.BPutObject(tenant, "a.png", []byte("png"), true)
+	assert.Nil(t, err)
+	stat, err := OssClient.StatObject(tenant, "a.png")
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", stat.ContentType)
+	assert.Equal(t, int64(3), stat.Size)
+	assert.Empty(t, stat.Metadata)
+
+	err = OssClient.BPutObject(tenant, "a.png", []byte("data"), true, PutOptions{
+		ContentType:        "text/plain",
+		ContentDisposition: "attachment; filename=\"a.txt\"",
+		CacheControl:       "no-cache",
+		Metadata:           map[string]string{"Owner": "zhubin"},
+	})
+	assert.Nil(t, err)
+	stat, err = OssClient.StatObject(tenant, "a.png")
+	assert.Nil(t, err)
+	assert.Equal(t, "text/plain", stat.ContentType)
+	assert.Equal(t, "attachment; filename=\"a.txt\"", stat.ContentDisposition)
+	assert.Equal(t, "no-cache", stat.CacheControl)
+	assert.Equal(t, map[string]string{"owner": "zhubin"}, stat.Metadata)
+
+	err = OssClient.RemoveObject(tenant, "a.png")
+	assert.Nil(t, err)
+	_, err = OssClient.StatObject(tenant, "a.png")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+// Fails uploading the parts from failAt on.
+type flakyDriver struct {
+	ObjectStorageDriver
+	failAt  int
+	uploads []int
+}
+
+func (d *flakyDriver) UploadPart(tenant string, objKey string, uploadID string, partNumber int, part io.Reader, size int64) (*PartInfo, error) {
+	if d.failAt > 0 && partNumber >= d.failAt {
+		return nil, errors.New("network is down")
+	}
+	d.uploads = append(d.uploads, partNumber)
+	return d.ObjectStorageDriver.UploadPart(tenant, objKey, uploadID, partNumber, part, size)
+}
+
+func TestLocal_UploadFile(t *testing.T) {
+	bucket = "localtest"
+	initConfig("Memory", "", "", "", "", bucket, false)
+	InitOssDriver(config.CONFIGS.OSS)
+
+	dir, err := ioutil.TempDir("", "oss")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	data := randgen.GenRandBytes(3500)
+	filePath := filepath.Join(dir, "video.mp4")
+	assert.Nil(t, ioutil.WriteFile(filePath, data, 0644))
+	cpFile := filepath.Join(dir, "video.cp")
+
+	driver := &flakyDriver{ObjectStorageDriver: OssClient, failAt: 3}
+	opts := UploadOptions{PartSize: 1000, CheckpointFile: cpFile}
+	_, err = UploadFile(driver, tenant, "video.mp4", filePath, opts)
+	assert.NotNil(t, err)
+	assert.Equal(t, []int{1, 2}, driver.uploads)
+	uploads, err := OssClient.ListMultipartUploads(tenant, "video")
+	assert.Nil(t, err)
+	assert.Len(t, uploads, 1)
+
+	// resume from part 3
+	var progress []int64
+	driver.failAt, driver.uploads = 0, nil
+	opts.Progress = func(uploaded int64, total int64) {
+		assert.Equal(t, int64(len(data)), total)
+		progress = append(progress, uploaded)
+	}
+	fileHash, err := UploadFile(driver, tenant, "video.mp4", filePath, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{3, 4}, driver.uploads)
+	assert.Equal(t, []int64{1000, 2000, 3000, 3500}, progress)
+
+	expected := hash.NewFileHash()
+	expected.Update(data)
+	assert.Equal(t, expected.Sum(nil), fileHash)
+
+	ret, err := OssClient.ReadObject(tenant, "video.mp4")
+	assert.Nil(t, err)
+	assert.Equal(t, data, ret)
+	stat, err := OssClient.StatObject(tenant, "video.mp4")
+	assert.Nil(t, err)
+	assert.Equal(t, "video/mp4", stat.ContentType)
+
+	uploads, err = OssClient.ListMultipartUploads(tenant, "")
+	assert.Nil(t, err)
+	assert.Empty(t, uploads)
+	_, err = os.Stat(cpFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestErrorKind(t *testing.T) {
+	assert.Equal(t, ErrNotFound, minioError(minio.ErrorResponse{Code: "NoSuchKey"}))
+	assert.Equal(t, ErrUploadNotFound, minioError(minio.ErrorResponse{Code: "NoSuchUpload"}))
+	assert.True(t, IsThrottled(minioError(minio.ErrorResponse{Code: "503 Service Unavailable"})))
+	assert.True(t, IsThrottled(minioError(minio.ErrorResponse{Code: "ServiceUnavailable"})))
+	assert.True(t, IsThrottled(aliyunError(aliyun.ServiceError{StatusCode: http.StatusServiceUnavailable})))
+	assert.True(t, IsAccessDenied(aliyunError(aliyun.ServiceError{StatusCode: http.StatusForbidden, Code: "AccessDenied"})))
+	assert.True(t, IsAlreadyExists(azureError(storage.AzureStorageServiceError{StatusCode: http.StatusConflict, Code: "BlobAlreadyExists"})))
+	assert.True(t, IsQuotaExceeded(azureError(storage.AzureStorageServiceError{StatusCode: http.StatusForbidden, Code: "AccountLimitExceeded"})))
+	assert.True(t, IsTransient(aliyunError(aliyun.ServiceError{StatusCode: http.StatusInternalServerError})))
+	assert.True(t, IsPreconditionFailed(minioError(minio.ErrorResponse{Code: "PreconditionFailed"})))
+	assert.True(t, IsPreconditionFailed(azureError(storage.AzureStorageServiceError{StatusCode: http.StatusPreconditionFailed, Code: "ConditionNotMet"})))
+
+	assert.True(t, IsRetryable(minioError(minio.ErrorResponse{Code: "SlowDown"})))
+	assert.True(t, IsRetryable(minioError(io.ErrUnexpectedEOF)))
+	assert.False(t, IsRetryable(ErrNotFound))
+	assert.False(t, IsRetryable(errors.New("oops")))
+	assert.Equal(t, "throttled", KindOf(minioError(minio.ErrorResponse{Code: "SlowDown"})).String())
+
+	initConfig("Memory", "", "", "", "", "localtest", false)
+	InitOssDriver(config.CONFIGS.OSS)
+	_, err := OssClient.ReadObject(tenant, "missing.txt")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestLocal_ConditionalPutObject(t *testing.T) {
+	initConfig("Memory", "", "", "", "", "localtest", false)
+	InitOssDriver(config.CONFIGS.OSS)
+
+	objKey := randgen.GenUniqueString(8)
+	ret, err := OssClient.ConditionalPutObject(tenant, objKey, strings.NewReader("v1"), PutCondition{IfNotExist: true})
+	assert.Nil(t, err)
+	assert.True(t, ret.Written)
+	etag := ret.ETag
+
+	ret, err = OssClient.ConditionalPutObject(tenant, objKey, strings.NewReader("v2"), PutCondition{IfNotExist: true})
+	assert.Nil(t, err)
+	assert.False(t, ret.Written)
+	assert.Nil(t, OssClient.BPutObject(tenant, objKey, []byte("v2"), false))
+	data, err := OssClient.ReadObject(tenant, objKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	// compare and swap
+	ret, err = OssClient.ConditionalPutObject(tenant, objKey, strings.NewReader("v2"), PutCondition{IfMatch: etag})
+	assert.Nil(t, err)
+	assert.True(t, ret.Written)
+	assert.NotEqual(t, etag, ret.ETag)
+	ret, err = OssClient.ConditionalPutObject(tenant, objKey, strings.NewReader("v3"), PutCondition{IfMatch: etag})
+	assert.Nil(t, err)
+	assert.False(t, ret.Written)
+	ret, err = OssClient.ConditionalPutObject(tenant, "missing.txt", strings.NewReader("v1"), PutCondition{IfMatch: etag})
+	assert.Nil(t, err)
+	assert.False(t, ret.Written)
+	data, err = OssClient.ReadObject(tenant, objKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", string(data))
+
+	// only one of the concurrent creators wins
+	objKey = randgen.GenUniqueString(8)
+	results := make(chan bool, 10)
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			ret, err := OssClient.ConditionalPutObject(tenant, objKey, strings.NewReader(fmt.Sprint(i)), PutCondition{IfNotExist: true})
+			assert.Nil(t, err)
+			results <- ret.Written
+		}(i)
+	}
+	written := 0
+	for i := 0; i < 10; i++ {
+		if <-results {
+			written++
+		}
+	}
+	assert.Equal(t, 1, written)
+}
+
+// A backend which is unreachable.
+type downDriver struct {
+	ObjectStorageDriver
+}
+
+var errDown = &Error{Kind: KindTransient, Err: errors.New("connection refused")}
+
+func (d downDriver) BPutObject(tenant string, objKey string, objData []byte, override bool, options ...PutOptions) error {
+	return errDown
+}
+
+func (d downDriver) FPutObject(tenant string, objKey string, filePath string, override bool, options ...PutOptions) error {
+	return errDown
+}
+
+func (d downDriver) ReadObject(tenant string, objKey string) ([]byte, error) {
+	return nil, errDown
+}
+
+func TestMirror(t *testing.T) {
+	driver := New(&conf.OSS{
+		Platform:    "Mirror",
+		WriteQuorum: 2,
+		Replicas: []*conf.OSS{
+			{Platform: "Memory", Bucket: "primary"},
+			{Platform: "Memory", Bucket: "replica1"},
+			{Platform: "Memory", Bucket: "replica2"},
+		},
+	})
+	mirror := driver.(*Mirror)
+	primary, replica := mirror.backends[0], mirror.backends[1]
+
+	// quorum reached with one backend down
+	mirror.backends[2] = downDriver{mirror.backends[2]}
+	assert.Nil(t, driver.BPutObject(tenant, "a.txt", []byte("hello"), true))
+	assert.Nil(t, driver.PutObject(tenant, "b.txt", strings.NewReader("world"), true))
+	data, err := replica.ReadObject(tenant, "b.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "world", string(data))
+
+	// quorum missed
+	mirror.backends[1] = downDriver{replica}
+	err = driver.BPutObject(tenant, "c.txt", []byte("hello"), true)
+	assert.True(t, IsTransient(err))
+
+	// read falls back to the replicas
+	mirror.backends[0], mirror.backends[1] = downDriver{primary}, replica
+	data, err = driver.ReadObject(tenant, "a.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	_, err = driver.ReadObject(tenant, "missing.txt")
+	assert.True(t, IsTransient(err))
+	mirror.backends[0] = primary
+	mirror.backends[2] = mirror.backends[2].(downDriver).ObjectStorageDriver
+	_, err = driver.ReadObject(tenant, "missing.txt")
+	assert.Equal(t, ErrNotFound, err)
+
+	// conditional puts are decided by the primary, then replicated
+	ret, err := driver.ConditionalPutObject(tenant, "d.json", strings.NewReader("{}"), PutCondition{IfNotExist: true})
+	assert.Nil(t, err)
+	assert.True(t, ret.Written)
+	stat, err := replica.StatObject(tenant, "d.json")
+	assert.Nil(t, err)
+	assert.Equal(t, "application/json", stat.ContentType)
+	ret, err = driver.ConditionalPutObject(tenant, "d.json", strings.NewReader("[]"), PutCondition{IfNotExist: true})
+	assert.Nil(t, err)
+	assert.False(t, ret.Written)
+}
+
+func TestTiered(t *testing.T) {
+	driver := New(&conf.OSS{
+		Platform: "Tiered",
+		Cache:    &conf.OSS{Platform: "Memory", Bucket: "cache"},
+		Remote:   &conf.OSS{Platform: "Memory", Bucket: "remote"},
+	})
+	tiered := driver.(*Tiered)
+
+	assert.Nil(t, driver.BPutObject(tenant, "a.txt", []byte("v1"), true))
+	exist, err := tiered.cache.CheckExist(tenant, "a.txt")
+	assert.Nil(t, err)
+	assert.False(t, exist)
+
+	data, err := driver.ReadObject(tenant, "a.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", string(data))
+	exist, err = tiered.cache.CheckExist(tenant, "a.txt")
+	assert.Nil(t, err)
+	assert.True(t, exist)
+
+	// served from the cache even if the remote is down
+	tiered.remote = downDriver{tiered.remote}
+	obj, err := driver.GetObject(tenant, "a.txt")
+	assert.Nil(t, err)
+	data, err = ioutil.ReadAll(obj)
+	obj.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", string(data))
+	tiered.remote = tiered.remote.(downDriver).ObjectStorageDriver
+
+	// writes drop the cached copy
+	assert.Nil(t, driver.BPutObject(tenant, "a.txt", []byte("v2"), true))
+	data, err = driver.ReadObject(tenant, "a.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", string(data))
+	assert.Nil(t, driver.RemoveObject(tenant, "a.txt"))
+	_, err = driver.ReadObject(tenant, "a.txt")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+// Downloads the object, then waits for release before returning it.
+type slowDriver struct {
+	ObjectStorageDriver
+	downloaded chan struct{}
+	release    chan struct{}
+}
+
+func (d slowDriver) GetObject(tenant string, objKey string) (io.ReadCloser, error) {
+	data, err := d.ObjectStorageDriver.ReadObject(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	close(d.downloaded)
+	<-d.release
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestTiered_WriteWhileFilling(t *testing.T) {
+	tiered := NewTieredClient(NewMemLocalClient("cache"), NewMemLocalClient("remote"))
+	assert.Nil(t, tiered.BPutObject(tenant, "a.txt", []byte("v1"), true))
+	slow := slowDriver{tiered.remote, make(chan struct{}), make(chan struct{})}
+	tiered.remote = slow
+
+	done := make(chan []byte)
+	go func() {
+		data, err := tiered.ReadObject(tenant, "a.txt")
+		assert.Nil(t, err)
+		done <- data
+	}()
+	<-slow.downloaded
+	// v1 was downloaded, v2 is written before it reaches the cache
+	assert.Nil(t, tiered.BPutObject(tenant, "a.txt", []byte("v2"), true))
+	close(slow.release)
+	<-done
+
+	tiered.remote = slow.ObjectStorageDriver
+	data, err := tiered.ReadObject(tenant, "a.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", string(data))
+}
+
+func TestEncrypted(t *testing.T) {
+	keys := NewKeyring()
+	assert.Nil(t, keys.AddKey(tenant, "k1", []byte("0123456789abcdef0123456789abcdef")))
+	assert.NotNil(t, keys.AddKey(tenant, "bad", []byte("short")))
+	raw := NewMemLocalClient("encrypted")
+	driver := NewEncryptedClient(raw, keys)
+
+	for _, size := range []int{0, 100, encSegmentSize, 3*encSegmentSize + 1} {
+		data := bytes.Repeat([]byte("datamesh"), size/8+1)[:size]
+		objKey := fmt.Sprintf("%d.txt", size)
+		assert.Nil(t, driver.BPutObject(tenant, objKey, data, true, PutOptions{Metadata: map[string]string{"owner": "bob"}}))
+
+		stored, err := raw.ReadObject(tenant, objKey)
+		assert.Nil(t, err)
+		assert.NotContains(t, string(stored), "datamesh")
+		ret, err := driver.ReadObject(tenant, objKey)
+		assert.Nil(t, err)
+		assert.Equal(t, data, ret)
+
+		stat, err := driver.StatObject(tenant, objKey)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(size), stat.Size)
+		assert.Equal(t, map[string]string{"owner": "bob"}, stat.Metadata)
+	}
+
+	// tampered and truncated objects
+	objKey := fmt.Sprintf("%d.txt", 3*encSegmentSize+1)
+	stored, err := raw.ReadObject(tenant, objKey)
+	assert.Nil(t, err)
+	stat, err := raw.StatObject(tenant, objKey)
+	assert.Nil(t, err)
+	opts := PutOptions{Metadata: stat.Metadata}
+	stored[10] ^= 1
+	assert.Nil(t, raw.BPutObject(tenant, objKey, stored, true, opts))
+	_, err = driver.ReadObject(tenant, objKey)
+	assert.Equal(t, ErrDecrypt, err)
+	stored[10] ^= 1
+	assert.Nil(t, raw.BPutObject(tenant, objKey, stored[:encSegmentSize+encTagSize], true, opts))
+	_, err = driver.ReadObject(tenant, objKey)
+	assert.Equal(t, ErrDecrypt, err)
+	wrapped, err := base64.StdEncoding.DecodeString(stat.Metadata[encMetaKey])
+	assert.Nil(t, err)
+	wrapped[len(wrapped)-1] ^= 1
+	opts.Metadata[encMetaKey] = base64.StdEncoding.EncodeToString(wrapped)
+	assert.Nil(t, raw.BPutObject(tenant, objKey, stored, true, opts))
+	_, err = driver.GetObject(tenant, objKey)
+	assert.Equal(t, ErrDecrypt, err)
+
+	// key rotation
+	assert.Nil(t, keys.AddKey(tenant, "k2", []byte("fedcba9876543210")))
+	ret, err := driver.ReadObject(tenant, "100.txt")
+	assert.Nil(t, err)
+	rewrapped, err := driver.Rewrap(tenant, "100.txt")
+	assert.Nil(t, err)
+	assert.True(t, rewrapped)
+	stat, err = raw.StatObject(tenant, "100.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "k2", stat.Metadata[encMetaKeyID])
+	data, err := driver.ReadObject(tenant, "100.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, ret, data)
+	rewrapped, err = driver.Rewrap(tenant, "100.txt")
+	assert.Nil(t, err)
+	assert.False(t, rewrapped)
+
+	// tenants without master key are stored in plain
+	assert.Nil(t, driver.BPutObject("plain", "a.txt", []byte("hello"), true))
+	data, err = raw.ReadObject("plain", "a.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	_, err = driver.PresignedURL(tenant, "a.txt", http.MethodGet, time.Hour)
+	assert.Equal(t, ErrNotSupported, err)
+}
+
+func TestLocal_GetObjectRange(t *testing.T) {
+	driver := NewMemLocalClient("localtest")
+	data := []byte("0123456789")
+	assert.Nil(t, driver.BPutObject(tenant, "a.txt", data, true))
+
+	cases := []struct {
+		offset, length int64
+		expected       string
+	}{
+		{0, -1, "0123456789"},
+		{3, 4, "3456"},
+		{3, 0, "3456789"},
+		{8, 100, "89"},
+	}
+	for _, c := range cases {
+		obj, err := driver.GetObjectRange(tenant, "a.txt", c.offset, c.length)
+		assert.Nil(t, err)
+		ret, err := ioutil.ReadAll(obj)
+		obj.Close()
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, string(ret))
+	}
+	_, err := driver.GetObjectRange(tenant, "a.txt", 10, 1)
+	assert.Equal(t, ErrInvalidRange, err)
+	_, err = driver.GetObjectRange(tenant, "missing.txt", 1, 1)
+	assert.Equal(t, ErrNotFound, err)
+
+	obj, err := OpenObject(driver, tenant, "a.txt")
+	assert.Nil(t, err)
+	defer obj.Close()
+	buf := make([]byte, 4)
+	_, err = obj.Seek(-3, io.SeekEnd)
+	assert.Nil(t, err)
+	n, err := io.ReadFull(obj, buf[:3])
+	assert.Nil(t, err)
+	assert.Equal(t, "789", string(buf[:n]))
+	n, err = obj.ReadAt(buf, 8)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "89", string(buf[:n]))
+
+	// http Range requests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		obj, err := OpenObject(driver, tenant, "a.txt")
+		assert.Nil(t, err)
+		defer obj.Close()
+		http.ServeContent(w, r, "a.txt", obj.Stat().LastModified, obj)
+	}))
+	defer server.Close()
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.Nil(t, err)
+	req.Header.Set("Range", "bytes=2-5")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	ret, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "2345", string(ret))
+}
+
+func TestEncrypted_GetObjectRange(t *testing.T) {
+	keys := NewKeyring()
+	assert.Nil(t, keys.AddKey(tenant, "k1", []byte("0123456789abcdef")))
+	driver := NewEncryptedClient(NewMemLocalClient("encrypted"), keys)
+	data := []byte(strings.Repeat("0123456789", encSegmentSize/4))
+	assert.Nil(t, driver.BPutObject(tenant, "a.txt", data, true))
+
+	for _, r := range [][2]int64{{0, 10}, {encSegmentSize - 5, 10}, {2*encSegmentSize + 7, 0}, {100, 3 * encSegmentSize}} {
+		obj, err := driver.GetObjectRange(tenant, "a.txt", r[0], r[1])
+		assert.Nil(t, err)
+		ret, err := ioutil.ReadAll(obj)
+		obj.Close()
+		assert.Nil(t, err)
+		end := int64(len(data))
+		if r[1] > 0 && r[0]+r[1] < end {
+			end = r[0] + r[1]
+		}
+		assert.Equal(t, data[r[0]:end], ret)
+	}
+	_, err := driver.GetObjectRange(tenant, "a.txt", int64(len(data)), 1)
+	assert.Equal(t, ErrInvalidRange, err)
+}
+
+// In memory blob service, enough for the multipart uploads of Azure.
+type fakeAzure struct {
+	mu          sync.Mutex
+	blobs       map[string][]byte
+	headers     map[string]http.Header
+	uncommitted map[string]map[string][]byte
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	name := r.URL.Path
+	query := r.URL.Query()
+	body, _ := ioutil.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		if f.uncommitted[name] == nil {
+			f.uncommitted[name] = make(map[string][]byte)
+		}
+		f.uncommitted[name][query.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		var list struct {
+			Blocks []string `xml:"Uncommitted"`
+		}
+		xml.Unmarshal(body, &list)
+		var data []byte
+		for _, id := range list.Blocks {
+			data = append(data, f.uncommitted[name][id]...)
+		}
+		f.blobs[name], f.headers[name] = data, r.Header
+		delete(f.uncommitted, name)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut:
+		f.blobs[name], f.headers[name] = body, r.Header
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && query.Get("comp") == "list":
+		// the continuation token is the name of the next blob
+		var names []string
+		for blob := range f.blobs {
+			if strings.HasPrefix(blob, name+"/"+query.Get("prefix")) && blob >= name+"/"+strings.TrimPrefix(query.Get("marker"), "token:") {
+				names = append(names, blob)
+			}
+		}
+		sort.Strings(names)
+		next := ""
+		if max, _ := strconv.Atoi(query.Get("maxresults")); max > 0 && len(names) > max {
+			next = "token:" + strings.TrimPrefix(names[max], name+"/")
+			names = names[:max]
+		}
+		var blobs []string
+		for _, blob := range names {
+			blobs = append(blobs, fmt.Sprintf("<Blob><Name>%s</Name><Properties><Last-Modified>Mon, 02 Jan 2006 15:04:05 GMT</Last-Modified>"+
+				"<Etag>0x1</Etag><Content-Length>%d</Content-Length></Properties></Blob>", strings.TrimPrefix(blob, name+"/"), len(f.blobs[blob])))
+		}
+		fmt.Fprintf(w, "<EnumerationResults><Blobs>%s</Blobs><NextMarker>%s</NextMarker></EnumerationResults>", strings.Join(blobs, ""), next)
+	case r.Method == http.MethodGet && query.Get("comp") == "blocklist":
+		var blocks []string
+		for id, block := range f.uncommitted[name] {
+			blocks = append(blocks, fmt.Sprintf("<Block><Name>%s</Name><Size>%d</Size></Block>", id, len(block)))
+		}
+		fmt.Fprintf(w, "<BlockList><CommittedBlocks/><UncommittedBlocks>%s</UncommittedBlocks></BlockList>", strings.Join(blocks, ""))
+	case r.Method == http.MethodGet:
+		data, ok := f.blobs[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>BlobNotFound</Code><Message>The specified blob does not exist.</Message></Error>")
+			return
+		}
+		w.Write(data)
+	case r.Method == http.MethodDelete:
+		if _, ok := f.blobs[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// Sends the requests to the fake service whatever the host.
+type fakeAzureTransport struct {
+	url *url.URL
+}
+
+func (t fakeAzureTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme, r.URL.Host = t.url.Scheme, t.url.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func newFakeAzureClient(t *testing.T, serverURL string) *Azure {
+	client, err := storage.NewClient("datameshtest", "MDEyMzQ1Njc4OWFiY2RlZg==", "core.chinacloudapi.cn", "2016-05-31", false)
+	assert.Nil(t, err)
+	u, err := url.Parse(serverURL)
+	assert.Nil(t, err)
+	client.HTTPClient = &http.Client{Transport: fakeAzureTransport{url: u}}
+	blobService := client.GetBlobService()
+	return &Azure{client: blobService.GetContainerReference("datameshtest")}
+}
+
+func TestAzure_ListObjects(t *testing.T) {
+	fake := &fakeAzure{
+		blobs:       make(map[string][]byte),
+		headers:     make(map[string]http.Header),
+		uncommitted: make(map[string]map[string][]byte),
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	driver := newFakeAzureClient(t, server.URL)
+	local := NewMemLocalClient("localtest")
+
+	keys := []string{"a/1", "a/2", "a/3", "b/1", "c"}
+	for _, key := range keys {
+		assert.Nil(t, driver.BPutObject(tenant, key, []byte(key), true))
+		assert.Nil(t, local.BPutObject(tenant, key, []byte(key), true))
+	}
+	assert.Nil(t, driver.BPutObject("ttt", "a/4", []byte("a/4"), true))
+
+	var listed []string
+	marker := ""
+	for {
+		ret, err := driver.ListObjects(tenant, "", marker, 2)
+		assert.Nil(t, err)
+		for _, obj := range ret.Objects {
+			listed = append(listed, obj.Key)
+			assert.Equal(t, int64(len(obj.Key)), obj.Size)
+		}
+		if ret.NextMarker == "" {
+			break
+		}
+		marker = ret.NextMarker
+	}
+	assert.Equal(t, keys, listed)
+
+	// continued from the marker of another driver
+	ret, err := local.ListObjects(tenant, "", "", 2)
+	assert.Nil(t, err)
+	ret, err = driver.ListObjects(tenant, "", ret.NextMarker, 2)
+	assert.Nil(t, err)
+	assert.Len(t, ret.Objects, 2)
+	assert.Equal(t, "a/3", ret.Objects[0].Key)
+	ret, err = local.ListObjects(tenant, "", ret.NextMarker, 0)
+	assert.Nil(t, err)
+	assert.Len(t, ret.Objects, 1)
+	assert.Equal(t, "c", ret.Objects[0].Key)
+}
+
+func TestAzure_MultipartUpload(t *testing.T) {
+	fake := &fakeAzure{
+		blobs:       make(map[string][]byte),
+		headers:     make(map[string]http.Header),
+		uncommitted: make(map[string]map[string][]byte),
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	driver := newFakeAzureClient(t, server.URL)
+	opts := PutOptions{ContentType: "video/mp4", Metadata: map[string]string{"owner": "bob"}}
+	uploadID, err := driver.InitiateMultipartUpload(tenant, "video.mp4", opts)
+	assert.Nil(t, err)
+	parts, err := driver.ListParts(tenant, "video.mp4", uploadID)
+	assert.Nil(t, err)
+	assert.Empty(t, parts)
+	_, err = driver.UploadPart(tenant, "video.mp4", uploadID, 1, strings.NewReader("0123"), 4)
+	assert.Nil(t, err)
+	_, err = driver.UploadPart(tenant, "video.mp4", uploadID, 2, strings.NewReader("45"), 2)
+	assert.Nil(t, err)
+
+	// completed after a restart
+	driver = newFakeAzureClient(t, server.URL)
+	parts, err = driver.ListParts(tenant, "video.mp4", uploadID)
+	assert.Nil(t, err)
+	assert.Len(t, parts, 2)
+	assert.Nil(t, driver.CompleteMultipartUpload(tenant, "video.mp4", uploadID, parts))
+	assert.Equal(t, ErrUploadNotFound, driver.CompleteMultipartUpload(tenant, "video.mp4", uploadID, parts))
+
+	blob := "/datameshtest/" + tenant + "/video.mp4"
+	assert.Equal(t, "012345", string(fake.blobs[blob]))
+	assert.Equal(t, "video/mp4", fake.headers[blob].Get("x-ms-blob-content-type"))
+	assert.Equal(t, "bob", fake.headers[blob].Get("x-ms-meta-owner"))
+	assert.Len(t, fake.blobs, 1)
+	_, err = driver.ListParts(tenant, "video.mp4", uploadID)
+	assert.Equal(t, ErrUploadNotFound, err)
+
+	uploadID, err = driver.InitiateMultipartUpload(tenant, "video.mp4", opts)
+	assert.Nil(t, err)
+	assert.Nil(t, driver.AbortMultipartUpload(tenant, "video.mp4", uploadID))
+	_, err = driver.ListParts(tenant, "video.mp4", uploadID)
+	assert.Equal(t, ErrUploadNotFound, err)
+	assert.Equal(t, ErrUploadNotFound, driver.CompleteMultipartUpload(tenant, "video.mp4", uploadID, nil))
+	assert.Len(t, fake.blobs, 1)
+}
@@ -0,0 +1,124 @@
+package oss
+
+import (
+	"errors"
+	"io"
+)
+
+var errNegativeOffset = errors.New("oss: negative offset")
+
+// Seekable handle of an object, e.g. to serve http Range requests:
+//
+//	obj, err := oss.OpenObject(oss.OssClient, tenant, objKey)
+//	...
+//	defer obj.Close()
+//	http.ServeContent(w, r, objKey, obj.Stat().LastModified, obj)
+//
+// Data is fetched lazily with ranged requests, a Seek drops the current one
+// and the next Read starts a new one from the new offset.
+// NOTE it is not safe for concurrent use, except ReadAt.
+type ObjectReader struct {
+	driver ObjectStorageDriver
+	tenant string
+	objKey string
+	stat   *ObjectStat
+	offset int64
+	body   io.ReadCloser
+}
+
+// if object not found, return oss.ErrNotFound as error.
+func OpenObject(driver ObjectStorageDriver, tenant string, objKey string) (*ObjectReader, error) {
+	stat, err := driver.StatObject(tenant, objKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectReader{driver: driver, tenant: tenant, objKey: objKey, stat: stat}, nil
+}
+
+// Properties of the object when it was opened.
+func (r *ObjectReader) Stat() *ObjectStat {
+	return r.stat
+}
+
+func (r *ObjectReader) Size() int64 {
+	return r.stat.Size
+}
+
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.stat.Size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.driver.GetObjectRange(r.tenant, r.objKey, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.stat.Size
+	}
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+	if offset != r.offset {
+		r.closeBody()
+		r.offset = offset
+	}
+	return offset, nil
+}
+
+// Read len(p) bytes from off with a request of its own, the offset of Read is not changed.
+func (r *ObjectReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	if off >= r.stat.Size {
+		return 0, io.EOF
+	}
+	body, err := r.driver.GetObjectRange(r.tenant, r.objKey, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	n, err := io.ReadFull(body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r *ObjectReader) Close() error {
+	return r.closeBody()
+}
+
+func (r *ObjectReader) closeBody() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Limit obj to length bytes if length > 0, for the platforms which can not express every range.
+func limitRange(obj io.ReadCloser, length int64) io.ReadCloser {
+	if length <= 0 {
+		return obj
+	}
+	return readCloser{Reader: io.LimitReader(obj, length), Closer: obj}
+}
@@ -0,0 +1,190 @@
+package oss
+
+import (
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+)
+
+// A fast cache, usually Local, in front of a remote backend.
+// Objects are downloaded to the cache when first read, and dropped from it when written or removed.
+// Everything else, including StatObject, goes to the remote backend so that the ETags stay those of the remote.
+// NOTE changes made to the remote backend by other processes are not seen while the object is cached,
+// it suits objects which never change once written, e.g. content addressed ones.
+// NOTE nothing is evicted from the cache, clean it up from outside if it grows too big.
+type Tiered struct {
+	cache  ObjectStorageDriver
+	remote ObjectStorageDriver
+
+	// Generations of the objects, hashed into stripes and bumped by every invalidation,
+	// so that an object downloaded before a write is not left in the cache after it.
+	genMu sync.Mutex
+	gens  [tieredGenStripes]uint64
+}
+
+const tieredGenStripes = 256
+
+func NewTieredClient(cache ObjectStorageDriver, remote ObjectStorageDriver) *Tiered {
+	if cache == nil || remote == nil {
+		panic("Uninitialized oss driver.")
+	}
+	return &Tiered{cache: cache, remote: remote}
+}
+
+// Download the object to the cache if missing.
+// If it was written meanwhile, the download may be the previous version and is dropped again.
+func (t *Tiered) fill(tenant string, objKey string) error {
+	gen := t.generation(tenant, objKey)
+	exist, err := t.cache.CheckExist(tenant, objKey)
+	if err != nil || exist {
+		return err
+	}
+	obj, err := t.remote.GetObject(tenant, objKey)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	if err := t.cache.PutObject(tenant, objKey, obj, false); err != nil {
+		return err
+	}
+	if t.generation(tenant, objKey) != gen {
+		return t.cache.RemoveObject(tenant, objKey)
+	}
+	return nil
+}
+
+// The generation is bumped before the object is removed from the cache,
+// so a fill either sees it changed or has its download removed.
+func (t *Tiered) invalidate(tenant string, objKey string, err error) error {
+	if err != nil {
+		return err
+	}
+	t.genMu.Lock()
+	t.gens[t.stripe(tenant, objKey)]++
+	t.genMu.Unlock()
+	return t.cache.RemoveObject(tenant, objKey)
+}
+
+func (t *Tiered) stripe(tenant string, objKey string) int {
+	h := fnv.New32a()
+	h.Write([]byte(tenant + "/" + objKey))
+	return int(h.Sum32() % tieredGenStripes)
+}
+
+func (t *Tiered) generation(tenant string, objKey string) uint64 {
+	t.genMu.Lock()
+	defer t.genMu.Unlock()
+	return t.gens[t.stripe(tenant, objKey)]
+}
+
+func (t *Tiered) PutObject(tenant string, objKey string, object io.Reader, overwrite bool, options ...PutOptions) error {
+	return t.invalidate(tenant, objKey, t.remote.PutObject(tenant, objKey, object, overwrite, options...))
+}
+
+func (t *Tiered) FPutObject(tenant string, objKey string, filePath string, overwrite bool, options ...PutOptions) error {
+	return t.invalidate(tenant, objKey, t.remote.FPutObject(tenant, objKey, filePath, overwrite, options...))
+}
+
+func (t *Tiered) BPutObject(tenant string, objKey string, objData []byte, overwrite bool, options ...PutOptions) error {
+	return t.invalidate(tenant, objKey, t.remote.BPutObject(tenant, objKey, objData, overwrite, options...))
+}
+
+// The remote object is served directly if it can not be cached.
+func (t *Tiered) GetObject(tenant string, objKey string) (io.ReadCloser, error) {
+	if err := t.fill(tenant, objKey); err != nil {
+		if IsNotFound(err) {
+			return nil, err
+		}
+		return t.remote.GetObject(tenant, objKey)
+	}
+	obj, err := t.cache.GetObject(tenant, objKey)
+	if err != nil {
+		return t.remote.GetObject(tenant, objKey)
+	}
+	return obj, nil
+}
+
+// NOTE the whole object is downloaded to the cache on the first read.
+func (t *Tiered) GetObjectRange(tenant string, objKey string, offset int64, length int64) (io.ReadCloser, error) {
+	if err := t.fill(tenant, objKey); err != nil {
+		if IsNotFound(err) {
+			return nil, err
+		}
+		return t.remote.GetObjectRange(tenant, objKey, offset, length)
+	}
+	obj, err := t.cache.GetObjectRange(tenant, objKey, offset, length)
+	if err == nil || err == ErrInvalidRange {
+		return obj, err
+	}
+	return t.remote.GetObjectRange(tenant, objKey, offset, length)
+}
+
+func (t *Tiered) ReadObject(tenant string, objKey string) ([]byte, error) {
+	if err := t.fill(tenant, objKey); err != nil {
+		if IsNotFound(err) {
+			return nil, err
+		}
+		return t.remote.ReadObject(tenant, objKey)
+	}
+	data, err := t.cache.ReadObject(tenant, objKey)
+	if err != nil {
+		return t.remote.ReadObject(tenant, objKey)
+	}
+	return data, nil
+}
+
+func (t *Tiered) RemoveObject(tenant string, objKey string) error {
+	return t.invalidate(tenant, objKey, t.remote.RemoveObject(tenant, objKey))
+}
+
+func (t *Tiered) CheckExist(tenant string, objKey string) (bool, error) {
+	if exist, err := t.cache.CheckExist(tenant, objKey); err == nil && exist {
+		return true, nil
+	}
+	return t.remote.CheckExist(tenant, objKey)
+}
+
+func (t *Tiered) StatObject(tenant string, objKey string) (*ObjectStat, error) {
+	return t.remote.StatObject(tenant, objKey)
+}
+
+func (t *Tiered) ListObjects(tenant string, prefix string, marker string, limit int) (*ListObjectsResult, error) {
+	return t.remote.ListObjects(tenant, prefix, marker, limit)
+}
+
+func (t *Tiered) PresignedURL(tenant string, objKey string, method string, expires time.Duration) (string, error) {
+	return t.remote.PresignedURL(tenant, objKey, method, expires)
+}
+
+func (t *Tiered) ConditionalPutObject(tenant string, objKey string, object io.Reader, cond PutCondition, options ...PutOptions) (*PutResult, error) {
+	ret, err := t.remote.ConditionalPutObject(tenant, objKey, object, cond, options...)
+	if err != nil || !ret.Written {
+		return ret, err
+	}
+	return ret, t.invalidate(tenant, objKey, nil)
+}
+
+func (t *Tiered) InitiateMultipartUpload(tenant string, objKey string, options ...PutOptions) (string, error) {
+	return t.remote.InitiateMultipartUpload(tenant, objKey, options...)
+}
+
+func (t *Tiered) UploadPart(tenant string, objKey string, uploadID string, partNumber int, part io.Reader, size int64) (*PartInfo, error) {
+	return t.remote.UploadPart(tenant, objKey, uploadID, partNumber, part, size)
+}
+
+func (t *Tiered) ListParts(tenant string, objKey string, uploadID string) ([]PartInfo, error) {
+	return t.remote.ListParts(tenant, objKey, uploadID)
+}
+
+func (t *Tiered) CompleteMultipartUpload(tenant string, objKey string, uploadID string, parts []PartInfo) error {
+	return t.invalidate(tenant, objKey, t.remote.CompleteMultipartUpload(tenant, objKey, uploadID, parts))
+}
+
+func (t *Tiered) AbortMultipartUpload(tenant string, objKey string, uploadID string) error {
+	return t.remote.AbortMultipartUpload(tenant, objKey, uploadID)
+}
+
+func (t *Tiered) ListMultipartUploads(tenant string, prefix string) ([]MultipartUpload, error) {
+	return t.remote.ListMultipartUploads(tenant, prefix)
+}